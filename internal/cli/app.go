@@ -1,6 +1,10 @@
 package cli
 
 import (
+	"time"
+
+	"duck/internal/config"
+
 	"github.com/urfave/cli/v2"
 )
 
@@ -12,6 +16,43 @@ func CreateApp() *cli.App {
 			"It scans your project structure and runs scripts across multiple applications " +
 			"while respecting dependencies.",
 		Version: "1.0.0",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "no-cache",
+				Usage: "Ignore the scan and dependency graph caches for this invocation",
+			},
+			&cli.BoolFlag{
+				Name:  "cache-scan",
+				Usage: "Use the scan cache for this invocation even if scanCache is not set in duck.yaml",
+			},
+			&cli.BoolFlag{
+				Name:  "no-default-ignores",
+				Usage: "Do not skip .git, node_modules, vendor, and dist directories while scanning",
+			},
+			&cli.BoolFlag{
+				Name:  "read-only",
+				Usage: "Fail any command that would write files; the scan cache is only read and run state is not recorded",
+			},
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "Path to the workspace config (default: duck.yaml in the current directory or the nearest parent that has one)",
+			},
+			&cli.StringFlag{
+				Name:  "color",
+				Value: "auto",
+				Usage: "Color status and list markers: auto (when stdout is a terminal and NO_COLOR is not set), always, or never",
+			},
+			&cli.BoolFlag{
+				Name:  "no-color",
+				Usage: "Do not color output (same as --color never, or setting NO_COLOR)",
+			},
+		},
+		EnableBashCompletion: true,
+		Before: func(c *cli.Context) error {
+			// Commands run from the workspace root, so they work in any
+			// subdirectory; init creates duck.yaml where it is run instead
+			return applyGlobalFlags(c, c.Args().First() != "init")
+		},
 		Commands: []*cli.Command{
 			{
 				Name:    "list",
@@ -28,74 +69,252 @@ func CreateApp() *cli.App {
 						Aliases: []string{"t"},
 						Usage:   "Filter projects by tag (can be used multiple times)",
 					},
+					&cli.StringFlag{
+						Name:  "filter",
+						Usage: "CEL expression selecting projects, e.g. 'namespace == \"backend\" && tag(\"worker\") && !tag(\"deprecated\")'",
+					},
 					&cli.BoolFlag{
 						Name:    "verbose",
 						Aliases: []string{"v"},
 						Usage:   "Show detailed project information",
 					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output format: 'text', 'json', or 'yaml'",
+						Value:   "text",
+					},
+					&cli.StringFlag{
+						Name:  "projects-output",
+						Usage: "Write the matching project keys to a file, one per line",
+					},
+					&cli.StringFlag{
+						Name:  "path-style",
+						Usage: "How to print project paths: 'relative' (to the workspace root) or 'absolute'",
+						Value: "relative",
+					},
+					&cli.StringFlag{
+						Name:  "depends-on",
+						Usage: "Only list projects that directly or transitively depend on this project",
+					},
+					&cli.StringFlag{
+						Name:  "depended-by",
+						Usage: "Only list projects this project directly or transitively depends on",
+					},
+					&cli.BoolFlag{
+						Name:  "changed",
+						Usage: "Only list projects with uncommitted changes (staged, unstaged, or untracked) in git",
+					},
+					&cli.StringFlag{
+						Name:  "since",
+						Usage: "Only list projects changed by git commits since a date or duration, e.g. '2026-10-01', '2 days ago' or '48h'",
+					},
 				},
 				Action: ListProjects,
 			},
 			{
-				Name:    "run",
-				Aliases: []string{"r"},
-				Usage:   "Run a script on projects",
+				Name:         "run",
+				Aliases:      []string{"r"},
+				Usage:        "Run a script on projects",
+				Flags:        runFlags(),
+				Action:       RunScript,
+				BashComplete: completeSelection,
+			},
+			{
+				Name:         "exec",
+				Usage:        "Run a command in each selected project's directory without defining a script",
+				ArgsUsage:    "-- <command> [args...]",
+				Flags:        execFlags(),
+				Action:       ExecCommand,
+				BashComplete: completeSelection,
+			},
+			{
+				Name:    "scripts",
+				Aliases: []string{"sc"},
+				Usage:   "List available scripts",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "verbose",
+						Aliases: []string{"v"},
+						Usage:   "Show detailed script information",
+					},
+					&cli.StringFlag{
+						Name:    "project",
+						Aliases: []string{"p"},
+						Usage:   "Only show the scripts enabled for this project (name, alias or key), with its overrides",
+					},
+					&cli.StringFlag{
+						Name:  "namespace",
+						Usage: "Show which scripts the projects of this namespace enable",
+					},
+				},
+				Action:       ListScripts,
+				BashComplete: completeSelection,
+			},
+			{
+				Name:      "tree",
+				Usage:     "Show the dependency tree of projects",
+				ArgsUsage: "[project...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "inverse",
+						Aliases: []string{"i"},
+						Usage:   "Show the projects that depend on each project instead of its dependencies",
+					},
+					&cli.IntFlag{
+						Name:    "depth",
+						Aliases: []string{"d"},
+						Usage:   "Maximum depth to render (0 for unlimited)",
+					},
+				},
+				Action: ShowTree,
+			},
+			{
+				Name:  "watch",
+				Usage: "Run a script on projects, then re-run it whenever they or their dependencies change",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:     "script",
 						Aliases:  []string{"s"},
-						Usage:    "Script name to run (required)",
+						Usage:    "Script to run",
 						Required: true,
 					},
+					&cli.BoolFlag{
+						Name:    "all",
+						Aliases: []string{"a"},
+						Usage:   "Watch all projects",
+					},
 					&cli.StringSliceFlag{
 						Name:    "project",
 						Aliases: []string{"p"},
-						Usage:   "Run on specific projects (namespace/name format)",
+						Usage:   "Watch specific projects (can be used multiple times)",
 					},
 					&cli.StringFlag{
 						Name:    "namespace",
 						Aliases: []string{"ns"},
-						Usage:   "Run on all projects in namespace",
+						Usage:   "Watch projects in a namespace",
 					},
 					&cli.StringSliceFlag{
 						Name:    "tag",
 						Aliases: []string{"t"},
-						Usage:   "Run on projects with specific tags",
+						Usage:   "Watch projects with specific tags",
 					},
-					&cli.BoolFlag{
-						Name:    "all",
-						Aliases: []string{"a"},
-						Usage:   "Run on all projects (respects dependency order)",
+					&cli.StringFlag{
+						Name:  "filter",
+						Usage: "CEL expression selecting projects (narrows other selectors)",
 					},
-					&cli.BoolFlag{
-						Name:    "dry-run",
-						Aliases: []string{"n"},
-						Usage:   "Show what would be executed without running",
+					&cli.DurationFlag{
+						Name:  "debounce",
+						Usage: "How long files must stay unchanged before the script re-runs",
+						Value: 300 * time.Millisecond,
 					},
 					&cli.BoolFlag{
 						Name:    "verbose",
 						Aliases: []string{"v"},
 						Usage:   "Show detailed execution output",
 					},
+				},
+				Action:       WatchProjects,
+				BashComplete: completeSelection,
+			},
+			{
+				Name:  "graph",
+				Usage: "Export the project dependency graph as a DOT or mermaid diagram",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "Diagram format: 'dot' or 'mermaid'",
+						Value:   "dot",
+					},
+					&cli.StringFlag{
+						Name:  "focus",
+						Usage: "Only export this project and its dependencies and dependents",
+					},
+					&cli.IntFlag{
+						Name:    "depth",
+						Aliases: []string{"d"},
+						Usage:   "With --focus, how many hops to follow in each direction (0 for unlimited)",
+					},
+					&cli.StringFlag{
+						Name:  "cluster-by",
+						Usage: "Group projects by 'namespace', 'tag' (their first tag), or 'none'",
+						Value: "namespace",
+					},
+					&cli.StringFlag{
+						Name:  "tag-prefix",
+						Usage: "With --cluster-by tag, group by the first tag with this prefix, e.g. 'tier:'",
+					},
+				},
+				Action: ShowGraph,
+			},
+			{
+				Name:      "why",
+				Usage:     "Explain how projects are connected through dependencies",
+				ArgsUsage: "<project>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dependents",
+						Usage: "List every project that directly or transitively depends on the project",
+					},
+				},
+				Action: ExplainDependents,
+			},
+			{
+				Name:      "info",
+				Usage:     "Show the configuration, scripts and dependents of a project",
+				ArgsUsage: "<project>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output format: 'text', 'json', or 'yaml'",
+						Value:   "text",
+					},
+					&cli.StringFlag{
+						Name:  "path-style",
+						Usage: "How to print the project path: 'relative' (to the workspace root) or 'absolute'",
+						Value: "relative",
+					},
+				},
+				Action:       ShowProjectInfo,
+				BashComplete: completeProjectArgument,
+			},
+			{
+				Name:  "init",
+				Usage: "Create a starter duck.yaml in the current directory",
+				Flags: []cli.Flag{
 					&cli.BoolFlag{
-						Name:  "parallel",
-						Usage: "Run on independent projects in parallel",
+						Name:    "force",
+						Aliases: []string{"f"},
+						Usage:   "Overwrite an existing duck.yaml",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Project configuration format: 'duck', 'nx', 'toml', 'all', or 'all+toml'",
+						Value: "duck",
+					},
+					&cli.StringFlag{
+						Name:  "target-directory",
+						Usage: "Directory to scan for projects (defaults to ./apps when it exists)",
 					},
 				},
-				Action: RunScript,
+				Action: InitWorkspace,
 			},
 			{
-				Name:    "scripts",
-				Aliases: []string{"sc"},
-				Usage:   "List available scripts",
+				Name:  "validate",
+				Usage: "Check project configuration for errors",
 				Flags: []cli.Flag{
 					&cli.BoolFlag{
-						Name:    "verbose",
-						Aliases: []string{"v"},
-						Usage:   "Show detailed script information",
+						Name:  "strict",
+						Usage: "Treat warnings (such as empty descriptions) as errors",
+					},
+					&cli.BoolFlag{
+						Name:  "check-scripts",
+						Usage: "Also lint script commands for common mistakes (cd, bash-only syntax, missing executables, empty commands)",
 					},
 				},
-				Action: ListScripts,
+				Action: ValidateConfig,
 			},
 			{
 				Name:  "config",
@@ -108,13 +327,24 @@ func CreateApp() *cli.App {
 							&cli.StringFlag{
 								Name:    "set",
 								Aliases: []string{"s"},
-								Usage:   "Set format to 'duck' or 'nx'",
+								Usage:   "Set format to 'duck', 'nx', 'toml', 'all', or 'all+toml'",
 							},
 						},
 						Action: ConfigFormat,
 					},
 				},
 			},
+			{
+				Name:  "cache",
+				Usage: "Manage Duck caches",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "clear",
+						Usage:  "Remove the project scan cache and the dependency graph cache",
+						Action: ClearCache,
+					},
+				},
+			},
 			{
 				Name:    "deps",
 				Aliases: []string{"dependencies"},
@@ -139,9 +369,331 @@ func CreateApp() *cli.App {
 						Name:  "sync",
 						Usage: "Sync discovered dependencies to app.yaml/project.json files",
 					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the dependency report as JSON",
+					},
+					&cli.BoolFlag{
+						Name:  "edges",
+						Usage: "Print one internal dependency per line as 'source<TAB>target<TAB>direct|indirect'",
+					},
+					&cli.BoolFlag{
+						Name:  "version-detail",
+						Usage: "With --json, report which direct dependency pulls in each indirect one (uses 'go mod graph')",
+					},
+					&cli.BoolFlag{
+						Name:  "conflicts",
+						Usage: "Report external modules that projects require at different versions",
+					},
+					&cli.BoolFlag{
+						Name:  "licenses",
+						Usage: "Group the external dependencies of Go projects by the license found in the module cache",
+					},
+					&cli.BoolFlag{
+						Name:  "updates",
+						Usage: "Report external Go dependencies with newer versions (queried via 'go list', honoring GOPROXY), listing new major versions separately",
+					},
+					&cli.StringFlag{
+						Name:  "since",
+						Usage: "Only analyze (and --sync) projects with changes since this git commit; falls back to all projects without git",
+					},
+					&cli.BoolFlag{
+						Name:  "no-cache",
+						Usage: "Analyze every project again instead of reusing the dependency graph cache in .duck/",
+					},
+					&cli.StringFlag{
+						Name:  "path-style",
+						Usage: "How to print project paths: 'relative' (to the workspace root) or 'absolute'",
+						Value: "relative",
+					},
 				},
 				Action: AnalyzeDependencies,
+				Subcommands: []*cli.Command{
+					{
+						Name:  "audit",
+						Usage: "Report go.mod requirements that no import uses and imported modules that go.mod does not require",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "workspace",
+								Aliases: []string{"w"},
+								Usage:   "Workspace root directory",
+								Value:   ".",
+							},
+							&cli.BoolFlag{
+								Name:  "json",
+								Usage: "Print the audit as JSON",
+							},
+							&cli.StringFlag{
+								Name:  "path-style",
+								Usage: "How to print project paths: 'relative' (to the workspace root) or 'absolute'",
+								Value: "relative",
+							},
+						},
+						Action: AuditDependencies,
+					},
+				},
 			},
+			{
+				Name:  "sbom",
+				Usage: "Export a CycloneDX SBOM of external dependencies across all projects",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "workspace",
+						Aliases: []string{"w"},
+						Usage:   "Workspace root directory",
+						Value:   ".",
+					},
+					&cli.StringFlag{
+						Name:  "lang",
+						Usage: "Only include dependencies of this language ('go', 'javascript') or 'all'",
+						Value: "all",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Write the SBOM to a file instead of stdout",
+					},
+				},
+				Action: GenerateSBOM,
+			},
+		},
+	}
+}
+
+// runFlags are the flags of run: the script, how projects are selected, and
+// how they run and are reported
+func runFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "script",
+			Aliases:  []string{"s"},
+			Usage:    "Script name to run (required)",
+			Required: true,
+		},
+		&cli.StringSliceFlag{
+			Name:    "project",
+			Aliases: []string{"p"},
+			Usage:   "Run on specific projects (namespace/name format)",
+		},
+		&cli.StringFlag{
+			Name:    "namespace",
+			Aliases: []string{"ns"},
+			Usage:   "Run on all projects in namespace",
+		},
+		&cli.StringSliceFlag{
+			Name:    "tag",
+			Aliases: []string{"t"},
+			Usage:   "Run on projects with specific tags",
+		},
+		&cli.BoolFlag{
+			Name:    "all",
+			Aliases: []string{"a"},
+			Usage:   "Run on all projects (respects dependency order)",
+		},
+		&cli.BoolFlag{
+			Name:  "reverse",
+			Usage: "With --all, run in teardown order (dependents before their dependencies)",
+		},
+		&cli.BoolFlag{
+			Name:  "abort-on-cycle",
+			Usage: "With --all, fail when the dependency graph contains a cycle (default)",
+		},
+		&cli.BoolFlag{
+			Name:  "warn-on-cycle",
+			Usage: "With --all, warn about dependency cycles and run the projects involved in arbitrary order",
+		},
+		&cli.StringFlag{
+			Name:  "filter",
+			Usage: "CEL expression selecting projects, e.g. 'size(deps) > 3 && \"api\" in tags' (narrows other selectors)",
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "Projects changed by git commits since a date or duration, e.g. '2026-10-01', '2 days ago' or '48h' (narrows other selectors)",
+		},
+		&cli.BoolFlag{
+			Name:  "with-deps",
+			Usage: "Also run on the transitive dependencies of the selected projects, in dependency order",
+		},
+		&cli.BoolFlag{
+			Name:    "dry-run",
+			Aliases: []string{"n"},
+			Usage:   "Show what would be executed without running",
+		},
+		&cli.BoolFlag{
+			Name:  "print-env",
+			Usage: "Print the complete environment the script would run with in each project, without running it",
+		},
+		&cli.BoolFlag{
+			Name:    "verbose",
+			Aliases: []string{"v"},
+			Usage:   "Show detailed execution output",
+		},
+		&cli.BoolFlag{
+			Name:  "parallel",
+			Usage: "Run on independent projects in parallel",
+		},
+		&cli.StringFlag{
+			Name:  "projects-output",
+			Usage: "Write the resolved project selection to a file, one key per line",
+		},
+		&cli.StringFlag{
+			Name:  "projects-from-file",
+			Usage: "Run on the project keys listed in a file (as written by --projects-output)",
+		},
+		&cli.StringFlag{
+			Name:  "projects-json",
+			Usage: "Run on the projects in a JSON array, in dependency order, with optional per-project overrides, e.g. '[{\"key\":\"api\",\"env\":{\"DEBUG\":\"1\"},\"timeout\":\"5m\",\"retries\":1}]'",
+		},
+		&cli.BoolFlag{
+			Name:  "only-failed-last-run",
+			Usage: "Run on the projects that failed the last time this script ran",
+		},
+		&cli.StringSliceFlag{
+			Name:  "env-file",
+			Usage: "Load KEY=VALUE pairs from a file into every project's environment (can be used multiple times)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "env",
+			Aliases: []string{"e"},
+			Usage:   "Set an environment variable (KEY=VALUE) for every project, overriding env files",
+		},
+		&cli.BoolFlag{
+			Name:  "isolate-env",
+			Usage: "Do not inherit duck's environment; scripts only get the configured variables and --env-allowlist",
+		},
+		&cli.StringSliceFlag{
+			Name:  "env-allowlist",
+			Usage: "Variables of duck's environment kept for isolated scripts (can be used multiple times)",
+			Value: cli.NewStringSlice("PATH", "HOME"),
+		},
+		&cli.StringSliceFlag{
+			Name:  "env-pass",
+			Usage: "Also pass this variable of duck's environment to isolated scripts, on top of --env-allowlist (can be used multiple times)",
+		},
+		&cli.StringFlag{
+			Name:  "before-each",
+			Usage: "Command to run in each project's directory before the script",
+		},
+		&cli.StringFlag{
+			Name:  "after-each",
+			Usage: "Command to run in each project's directory after the script (runs even on failure)",
+		},
+		&cli.DurationFlag{
+			Name:  "max-runtime-per-project",
+			Usage: "Warn (without stopping the script) when a project takes longer than this, e.g. 30s or 5m",
 		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Stop each attempt of the script after this long, e.g. 30s or 5m (overrides duck.yaml and app.yaml)",
+		},
+		&cli.IntFlag{
+			Name:  "retries",
+			Usage: "Number of additional attempts after a failed run (overrides duck.yaml and app.yaml)",
+		},
+		&cli.StringFlag{
+			Name:  "record-artifacts",
+			Usage: "After each successful project run, copy files matching this glob (relative to the project root, {projectName} is substituted) into --artifacts-dir",
+		},
+		&cli.StringFlag{
+			Name:  "artifacts-dir",
+			Usage: "Directory that --record-artifacts copies into, one subdirectory per project",
+			Value: "artifacts",
+		},
+		&cli.StringFlag{
+			Name:  "working-dir-strategy",
+			Usage: "Where scripts run: 'project' (their workingDir, relative to the project root) or 'workspace' (the workspace root, ignoring workingDir)",
+			Value: "project",
+		},
+		&cli.BoolFlag{
+			Name:  "continue-on-error",
+			Usage: "Keep running the remaining projects after a failure (skipping projects that depend on a failed one) and report all failures at the end",
+		},
+		&cli.DurationFlag{
+			Name:  "summary-threshold",
+			Usage: "Only list failed projects and projects that took longer than this (e.g. 30s) in the summary table",
+		},
+		&cli.BoolFlag{
+			Name:  "annotate-durations",
+			Usage: "Warn when a project takes much longer than its average recorded duration",
+		},
+		&cli.Float64Flag{
+			Name:  "regression-factor",
+			Usage: "With --annotate-durations, how many times slower than average a project must be to warn",
+			Value: 1.5,
+		},
+		&cli.BoolFlag{
+			Name:  "summary-on-signal",
+			Usage: "On Ctrl-C, stop starting projects, give the running one a moment to finish, and print a partial summary",
+		},
+		&cli.BoolFlag{
+			Name:  "keep-services",
+			Usage: "Leave the services started by readyWhen scripts running when duck exits; by default they are stopped however the run ends",
+		},
+		&cli.BoolFlag{
+			Name:  "no-summary",
+			Usage: "Do not print the summary table at the end of the run",
+		},
+		&cli.IntFlag{
+			Name:  "max-log-lines-per-project",
+			Usage: "Print at most this many lines of captured output per project, passed or failed (0 = no limit)",
+		},
+		&cli.IntFlag{
+			Name:  "output-lines",
+			Value: 200,
+			Usage: "Print only the first and last lines of longer output and error streams, omitting the middle (0 = no limit)",
+		},
+		&cli.BoolFlag{
+			Name:  "combine-output",
+			Usage: "Capture stdout and stderr as one stream in the order they were written",
+		},
+		&cli.BoolFlag{
+			Name:  "interactive",
+			Usage: "Connect the script to the terminal so it can read input; its output is not captured (single project only; default when running one project on a terminal)",
+		},
+		&cli.BoolFlag{
+			Name:  "events",
+			Usage: "Write newline-delimited JSON events (start, output, finish, skip, summary) to stdout instead of the human output",
+		},
+	}
+}
+
+// execFlags are the flags of run that apply to exec. The ones that need a
+// script name, for its settings or recorded runs, are left out.
+func execFlags() []cli.Flag {
+	scriptOnly := map[string]bool{
+		"script":               true,
+		"projects-json":        true,
+		"only-failed-last-run": true,
+		"annotate-durations":   true,
+		"regression-factor":    true,
+		"keep-services":        true,
+	}
+
+	var flags []cli.Flag
+	for _, flag := range runFlags() {
+		if !scriptOnly[flag.Names()[0]] {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// applyGlobalFlags stores the global flags and, with enterRoot, enters the
+// workspace root. It runs before every command, and before completing one,
+// which urfave/cli does without running Before.
+func applyGlobalFlags(c *cli.Context, enterRoot bool) error {
+	globalOptions = GlobalOptions{
+		NoCache:          c.Bool("no-cache"),
+		CacheScan:        c.Bool("cache-scan"),
+		NoDefaultIgnores: c.Bool("no-default-ignores"),
+		ReadOnly:         c.Bool("read-only"),
+		ConfigFile:       config.WorkspaceConfigFile,
+	}
+	if err := setupColor(c.String("color"), c.Bool("no-color")); err != nil {
+		return err
+	}
+	if !enterRoot {
+		return nil
 	}
+	return enterWorkspaceRoot(c.String("config"))
 }
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	goscan "duck/internal/dependencyscanner/go"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ModuleAuditInfo is the machine-readable audit of a Go project's go.mod
+type ModuleAuditInfo struct {
+	Project    string                 `json:"project"`
+	Module     string                 `json:"module"`
+	Unused     []UnusedRequireInfo    `json:"unused"`
+	Undeclared []UndeclaredImportInfo `json:"undeclared"`
+}
+
+// UnusedRequireInfo is a direct requirement that no import uses
+type UnusedRequireInfo struct {
+	Module  string `json:"module"`
+	Version string `json:"version,omitempty"`
+}
+
+// UndeclaredImportInfo is an imported module that go.mod does not require
+type UndeclaredImportInfo struct {
+	Module      string   `json:"module"`
+	ImportPaths []string `json:"importPaths"`
+}
+
+// AuditDependencies reports, for each Go project with a go.mod, the direct
+// requirements none of its imports use and the imported modules it does not
+// require
+func AuditDependencies(c *cli.Context) error {
+	absWorkspaceRoot, err := workspaceFlag(c)
+	if err != nil {
+		return err
+	}
+
+	paths, err := newPathFormatter(c.String("path-style"))
+	if err != nil {
+		return err
+	}
+	paths.workspaceRoot = absWorkspaceRoot
+
+	originalCwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	if err := os.Chdir(absWorkspaceRoot); err != nil {
+		return fmt.Errorf("failed to change to workspace directory: %w", err)
+	}
+	defer os.Chdir(originalCwd)
+
+	projectConfig, allProjects, err := LoadProjectData()
+	if err != nil {
+		return fmt.Errorf("failed to load project data: %w", err)
+	}
+
+	goScanner := goscan.NewGoScanner()
+	goScanner.SetIgnore(projectConfig.Ignore)
+
+	projectDirs := workspaceProjectDirs(absWorkspaceRoot, allProjects)
+	sort.Strings(projectDirs)
+
+	audits := make([]ModuleAuditInfo, 0, len(projectDirs))
+	for _, projectDir := range projectDirs {
+		projectPath := filepath.Join(absWorkspaceRoot, projectDir)
+		if !goScanner.CanScan(projectPath) {
+			continue
+		}
+
+		audit, err := goScanner.AuditModule(projectPath)
+		if err != nil {
+			return fmt.Errorf("failed to audit project %s: %w", projectDir, err)
+		}
+
+		info := ModuleAuditInfo{
+			Project:    paths.FormatKey(projectDir),
+			Module:     audit.Module,
+			Unused:     make([]UnusedRequireInfo, 0, len(audit.Unused)),
+			Undeclared: make([]UndeclaredImportInfo, 0, len(audit.Undeclared)),
+		}
+		for _, dep := range audit.Unused {
+			info.Unused = append(info.Unused, UnusedRequireInfo{Module: dep.Target, Version: dep.Version})
+		}
+		for module, importPaths := range audit.Undeclared {
+			info.Undeclared = append(info.Undeclared, UndeclaredImportInfo{Module: module, ImportPaths: importPaths})
+		}
+		sort.Slice(info.Undeclared, func(i, j int) bool {
+			return info.Undeclared[i].Module < info.Undeclared[j].Module
+		})
+		audits = append(audits, info)
+	}
+
+	if c.Bool("json") {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(audits)
+	}
+
+	if len(audits) == 0 {
+		fmt.Println("No Go projects with a go.mod found.")
+		return nil
+	}
+
+	withFindings := 0
+	for _, audit := range audits {
+		fmt.Println(audit.Project)
+
+		if len(audit.Unused) == 0 && len(audit.Undeclared) == 0 {
+			fmt.Println("   " + colorSuccess("✅ go.mod matches the imports"))
+			fmt.Println()
+			continue
+		}
+		withFindings++
+
+		if len(audit.Unused) > 0 {
+			fmt.Printf("   %s\n", colorWarning(fmt.Sprintf("Required but not imported (%d, candidates for 'go mod tidy'):", len(audit.Unused))))
+			for _, unused := range audit.Unused {
+				fmt.Printf("     - %s", unused.Module)
+				if unused.Version != "" {
+					fmt.Printf(" (%s)", unused.Version)
+				}
+				fmt.Println()
+			}
+		}
+
+		if len(audit.Undeclared) > 0 {
+			fmt.Printf("   %s\n", colorFailure(fmt.Sprintf("Imported but not required (%d):", len(audit.Undeclared))))
+			for _, undeclared := range audit.Undeclared {
+				fmt.Printf("     - %s: %s\n", undeclared.Module, strings.Join(undeclared.ImportPaths, ", "))
+			}
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("%d of %d Go project(s) have go.mod requirements out of sync with their imports\n", withFindings, len(audits))
+	return nil
+}
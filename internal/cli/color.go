@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+)
+
+// ANSI escape sequences used to color status and list markers
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+)
+
+// colorEnabled reports whether status markers are colored; it is set from
+// the global --color and --no-color flags by setupColor
+var colorEnabled bool
+
+// setupColor decides whether output is colored. With --color auto (the
+// default), colors are used when stdout is a terminal, unless --no-color is
+// passed or NO_COLOR is set. --color always colors output piped into tools that
+// render ANSI, and --color never disables colors.
+func setupColor(mode string, noColor bool) error {
+	switch mode {
+	case "", "auto":
+		colorEnabled = !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	case "always":
+		if noColor {
+			return fmt.Errorf("--no-color and --color always cannot be used together")
+		}
+		colorEnabled = true
+	case "never":
+		colorEnabled = false
+	default:
+		return fmt.Errorf("invalid --color: must be 'auto', 'always', or 'never', got '%s'", mode)
+	}
+	return nil
+}
+
+// colorize wraps text in the ANSI color code when colors are enabled
+func colorize(code, text string) string {
+	if !colorEnabled {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// colorSuccess marks passed projects and steps
+func colorSuccess(text string) string {
+	return colorize(ansiGreen, text)
+}
+
+// colorFailure marks failed projects and steps
+func colorFailure(text string) string {
+	return colorize(ansiRed, text)
+}
+
+// colorWarning marks skipped, interrupted and slow projects
+func colorWarning(text string) string {
+	return colorize(ansiYellow, text)
+}
+
+// colorHeading marks the namespace headings of the project list
+func colorHeading(text string) string {
+	return colorize(ansiBold, text)
+}
+
+// colorProject marks the projects of the project list
+func colorProject(text string) string {
+	return colorize(ansiCyan, text)
+}
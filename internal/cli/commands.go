@@ -1,21 +1,31 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"duck/internal/config"
+	"duck/internal/dependencyscanner"
 	goscan "duck/internal/dependencyscanner/go"
+	jsscan "duck/internal/dependencyscanner/js"
 	"duck/internal/executor"
 	"duck/internal/resolver"
+	"duck/internal/scanner"
+	"duck/internal/state"
 
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
 )
 
 func ListProjects(c *cli.Context) error {
@@ -24,13 +34,65 @@ func ListProjects(c *cli.Context) error {
 		return err
 	}
 
-	filtered := FilterProjects(projects, FilterOptions{
+	options := FilterOptions{
 		Namespace: c.String("namespace"),
 		Tags:      c.StringSlice("tag"),
-	})
+	}
+	if expression := c.String("filter"); expression != "" {
+		options.Match, err = compileFilter(expression, projects)
+		if err != nil {
+			return err
+		}
+	}
+
+	filtered := FilterProjects(projects, options)
+
+	filtered, err = filterByDependencies(filtered, projects, c.String("depends-on"), c.String("depended-by"))
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("changed") {
+		filtered, err = filterChangedProjects(filtered)
+		if err != nil {
+			return err
+		}
+	}
+
+	if since := c.String("since"); since != "" {
+		filtered, err = filterProjectsChangedSince(filtered, since)
+		if err != nil {
+			return err
+		}
+	}
+
+	paths, err := newPathFormatter(c.String("path-style"))
+	if err != nil {
+		return err
+	}
+
+	if outputPath := c.String("projects-output"); outputPath != "" {
+		var projectKeys []string
+		for key := range filtered {
+			projectKeys = append(projectKeys, key)
+		}
+		sort.Strings(projectKeys)
+
+		if err := writeProjectSelection(outputPath, projectKeys); err != nil {
+			return err
+		}
+	}
+
+	switch output := c.String("output"); output {
+	case "", "text":
+	case "json", "yaml":
+		return printProjects(filtered, output, paths)
+	default:
+		return fmt.Errorf("invalid output format: must be 'text', 'json', or 'yaml', got '%s'", output)
+	}
 
 	if len(filtered) == 0 {
-		fmt.Println("No projects found matching the criteria.")
+		fmt.Println(colorWarning("No projects found matching the criteria."))
 		return nil
 	}
 
@@ -46,7 +108,7 @@ func ListProjects(c *cli.Context) error {
 	verbose := c.Bool("verbose")
 
 	for _, namespace := range namespaces {
-		fmt.Printf("📁 %s\n", namespace)
+		fmt.Println(colorHeading("📁 " + namespace))
 
 		projects := organized[namespace]
 		sort.Slice(projects, func(i, j int) bool {
@@ -63,7 +125,7 @@ func ListProjects(c *cli.Context) error {
 				}
 			}
 
-			fmt.Printf("  🦆 %s", project.Config.Name)
+			fmt.Printf("  %s", colorProject("🦆 "+project.Config.Name))
 			// Show path in parentheses if it differs from name
 			if projectKey != "" && projectKey != project.Config.Name {
 				fmt.Printf(" (%s)", projectKey)
@@ -71,6 +133,9 @@ func ListProjects(c *cli.Context) error {
 			fmt.Println()
 
 			if verbose {
+				if len(project.Config.Aliases) > 0 {
+					fmt.Printf("     Aliases: %s\n", strings.Join(project.Config.Aliases, ", "))
+				}
 				if project.Config.Description != "" {
 					fmt.Printf("     Description: %s\n", project.Config.Description)
 				}
@@ -80,7 +145,7 @@ func ListProjects(c *cli.Context) error {
 				if len(project.Config.Tags) > 0 {
 					fmt.Printf("     Tags: %s\n", strings.Join(project.Config.Tags, ", "))
 				}
-				fmt.Printf("     Path: %s\n", project.Path)
+				fmt.Printf("     Path: %s\n", paths.Format(project.Path))
 			}
 		}
 		fmt.Println()
@@ -100,21 +165,857 @@ func RunScript(c *cli.Context) error {
 		return fmt.Errorf("script '%s' not found", scriptName)
 	}
 
+	return runProjects(c, projectConfig, projects, scriptName, true)
+}
+
+// ExecCommand runs the command given after -- in every selected project, as an
+// unnamed script with the same selection and reporting as run
+func ExecCommand(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("no command given; put it after --, e.g. duck exec --all -- go mod tidy")
+	}
+
+	projectConfig, projects, err := LoadProjectData()
+	if err != nil {
+		return err
+	}
+
+	// The command doubles as the script name in the output
+	command := execCommandLine(c.Args().Slice())
+	scripts := make(map[string]config.Script, len(projectConfig.Scripts)+1)
+	for name, script := range projectConfig.Scripts {
+		scripts[name] = script
+	}
+	scripts[command] = config.Script{Command: command}
+	projectConfig.Scripts = scripts
+
+	return runProjects(c, projectConfig, projects, command, false)
+}
+
+// execCommandLine turns the arguments of exec into the command it runs. A single
+// argument is a shell command line and is used as is, so that it can use shell
+// operators. Several arguments are quoted for a POSIX shell, so that each one
+// reaches the program unchanged.
+func execCommandLine(args []string) string {
+	if len(args) == 1 {
+		return args[0]
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote single-quotes arg unless it only contains characters a POSIX
+// shell takes literally
+func shellQuote(arg string) string {
+	safe := arg != ""
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=+@%{}", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// runProjects runs scriptName on the projects selected by c's flags and reports
+// the outcome. With recordResults, the results are saved for
+// --only-failed-last-run and --annotate-durations.
+func runProjects(c *cli.Context, projectConfig *config.ProjectConfig, projects map[string]*config.AppProject, scriptName string, recordResults bool) error {
+	// With --events, stdout carries JSON events instead of the human output
+	var out io.Writer = os.Stdout
+	var events *eventStream
+	if c.Bool("events") {
+		if c.Bool("dry-run") {
+			return fmt.Errorf("--events cannot be used with --dry-run")
+		}
+		if c.Bool("print-env") {
+			return fmt.Errorf("--events cannot be used with --print-env")
+		}
+		if c.Bool("interactive") {
+			return fmt.Errorf("--events cannot be used with --interactive")
+		}
+		out = io.Discard
+		events = newEventStream(os.Stdout)
+	}
+	runStart := time.Now()
+
+	targetProjects, err := selectTargetProjects(c, projects)
+	if err != nil {
+		return err
+	}
+
+	if outputPath := c.String("projects-output"); outputPath != "" {
+		if err := writeProjectSelection(outputPath, targetProjects); err != nil {
+			return err
+		}
+	}
+
+	if len(targetProjects) == 0 {
+		fmt.Fprintln(out, "No projects match the selection criteria.")
+		if events != nil {
+			events.summary(scriptName, 0, 0, 0, 0, false, time.Since(runStart))
+		}
+		return nil
+	}
+
+	environment, err := loadRunEnvironment(c.StringSlice("env-file"), c.StringSlice("env"))
+	if err != nil {
+		return err
+	}
+
+	outputMode := executor.OutputSeparate
+	if c.Bool("combine-output") {
+		outputMode = executor.OutputCombined
+	}
+
+	// Interactive scripts own the terminal, so only one project can run at a
+	// time and its output is not captured for the flags that shape it
+	interactive := c.Bool("interactive")
+	if interactive {
+		if len(targetProjects) > 1 {
+			return fmt.Errorf("--interactive can only be used with a single project, got %d", len(targetProjects))
+		}
+		for _, flag := range outputShapingFlags {
+			if c.IsSet(flag) {
+				return fmt.Errorf("--interactive cannot be used with --%s: the output is not captured", flag)
+			}
+		}
+	} else {
+		terminal := isTerminal(os.Stdin) && isTerminal(os.Stdout)
+		interactive = autoInteractive(c, len(targetProjects), projectConfig.Scripts[scriptName], terminal)
+	}
+	if interactive {
+		outputMode = executor.OutputInteractive
+	}
+
+	maxRuntime := c.Duration("max-runtime-per-project")
+	if maxRuntime < 0 {
+		return fmt.Errorf("--max-runtime-per-project must not be negative")
+	}
+
+	timeout := c.Duration("timeout")
+	if timeout < 0 {
+		return fmt.Errorf("--timeout must not be negative")
+	}
+
+	var retries *int
+	if c.IsSet("retries") {
+		value := c.Int("retries")
+		if value < 0 {
+			return fmt.Errorf("--retries must not be negative")
+		}
+		retries = &value
+	}
+
+	workingDirStrategy := executor.WorkingDirStrategy(c.String("working-dir-strategy"))
+	if workingDirStrategy != executor.WorkingDirProject && workingDirStrategy != executor.WorkingDirWorkspace {
+		return fmt.Errorf("invalid working dir strategy: must be 'project' or 'workspace', got '%s'", workingDirStrategy)
+	}
+
+	if c.String("record-artifacts") != "" && !c.Bool("dry-run") {
+		if err := checkWritable(c.String("artifacts-dir")); err != nil {
+			return err
+		}
+	}
+	if !c.Bool("dry-run") {
+		if err := checkServiceWritable(projectConfig.Scripts[scriptName]); err != nil {
+			return err
+		}
+	}
+
+	workspaceRoot, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	artifactsDir, err := filepath.Abs(c.String("artifacts-dir"))
+	if err != nil {
+		return fmt.Errorf("failed to resolve artifacts directory: %w", err)
+	}
+
+	var onEvent func(executor.Event)
+	if events != nil {
+		onEvent = events.executorEvent
+	}
+
+	runner := executor.NewWithOptions(projectConfig, projects, executor.Options{
+		Environment:        environment,
+		BeforeEach:         c.String("before-each"),
+		AfterEach:          c.String("after-each"),
+		MaxRuntime:         maxRuntime,
+		OutputMode:         outputMode,
+		Timeout:            timeout,
+		Retries:            retries,
+		RecordArtifacts:    c.String("record-artifacts"),
+		ArtifactsDir:       artifactsDir,
+		WorkingDirStrategy: workingDirStrategy,
+		WorkspaceRoot:      workspaceRoot,
+		IsolateEnv:         c.Bool("isolate-env"),
+		EnvAllowlist:       append(c.StringSlice("env-allowlist"), c.StringSlice("env-pass")...),
+		OnEvent:            onEvent,
+	})
+
+	if c.Bool("dry-run") {
+		return printDryRun(out, runner, scriptName, targetProjects, projects)
+	}
+	if c.Bool("print-env") {
+		return printEnvironment(out, runner, scriptName, targetProjects, projects)
+	}
+
+	// Services started by readyWhen scripts are stopped however the run
+	// ends, unless they are meant to outlive it
+	if c.Bool("keep-services") {
+		defer printServices(out, runner)
+	} else {
+		defer stopServices(out, runner)
+	}
+
+	// The app context is cancelled on SIGINT or SIGTERM, which kills the
+	// project in flight. With --summary-on-signal, that project instead gets
+	// a grace period to finish before it is killed.
+	interrupted := c.Context
+	ctx := c.Context
+	if c.Bool("summary-on-signal") {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.WithoutCancel(interrupted))
+		defer cancel()
+		go func() {
+			<-interrupted.Done()
+			time.AfterFunc(interruptGracePeriod, cancel)
+		}()
+	}
+
+	verbose := c.Bool("verbose")
+	var slowProjects []string
+
+	maxLogLines := c.Int("max-log-lines-per-project")
+	if maxLogLines < 0 {
+		return fmt.Errorf("--max-log-lines-per-project must not be negative")
+	}
+	outputLines := c.Int("output-lines")
+	if outputLines < 0 {
+		return fmt.Errorf("--output-lines must not be negative")
+	}
+
+	// With --annotate-durations, compare durations against the recorded history
+	var history *state.State
+	regressionFactor := c.Float64("regression-factor")
+	if c.Bool("annotate-durations") {
+		if regressionFactor <= 1 {
+			return fmt.Errorf("--regression-factor must be greater than 1")
+		}
+		history, err = state.Load(workspaceRoot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: not annotating durations: %v\n", err)
+		}
+	}
+
+	// Remember which projects failed so --only-failed-last-run can pick them
+	// up, and how long the passing ones took
+	var passed, failed []string
+	durations := make(map[string]time.Duration)
+	if recordResults {
+		defer recordRunResults(scriptName, &passed, &failed, durations)
+	}
+
+	// With --continue-on-error, projects whose dependencies failed or were
+	// skipped are skipped too; skipped maps them to the dependency responsible
+	continueOnError := c.Bool("continue-on-error")
+	failedSet := make(map[string]bool)
+	skipped := make(map[string]string)
+	var skippedOrder []string
+	dependencies := dependencyMap(projects)
+
+	summaryThreshold := c.Duration("summary-threshold")
+	if summaryThreshold < 0 {
+		return fmt.Errorf("--summary-threshold must not be negative")
+	}
+	var runs []projectRun
+	showSummary := !c.Bool("no-summary") && (len(targetProjects) > 1 || summaryThreshold > 0)
+
+	// emitSummary ends the --events stream, however the run ends
+	emitSummary := func(interrupted bool) {
+		if events != nil {
+			events.summary(scriptName, len(targetProjects), len(passed), len(failed), len(skippedOrder), interrupted, time.Since(runStart))
+		}
+	}
+
+	fmt.Fprintf(out, "Running script '%s' on %d project(s)...\n\n", scriptName, len(targetProjects))
+
+	// Projects run in batches: one at a time, or with --parallel a dependency
+	// level of the selection at once
+	batches := make([][]string, len(targetProjects))
+	for i, projectKey := range targetProjects {
+		batches[i] = []string{projectKey}
+	}
+	if c.Bool("parallel") {
+		batches, err = parallelBatches(projects, targetProjects, c.Bool("reverse"))
+		if err != nil {
+			return err
+		}
+	}
+	position := make(map[string]int, len(targetProjects))
+	for i, projectKey := range targetProjects {
+		position[projectKey] = i + 1
+	}
+
+	// report prints the outcome of a project and records it. It returns false
+	// when the project was interrupted.
+	var inFlight []string
+	failedOn := ""
+	report := func(projectKey string, result *executor.ExecutionResult, duration time.Duration) bool {
+		project := projects[projectKey]
+
+		if !result.Success && interrupted.Err() != nil {
+			fmt.Fprintf(out, " %s (%v)\n\n", colorWarning("⏹️  INTERRUPTED"), duration.Truncate(time.Millisecond))
+			runs = append(runs, projectRun{Key: projectKey, Duration: duration, Interrupted: true})
+			inFlight = append(inFlight, projectKey)
+			return false
+		}
+
+		runs = append(runs, projectRun{Key: projectKey, Duration: duration, Success: result.Success, ExitCode: result.ExitCode})
+
+		attempts := ""
+		if result.Attempts > 1 {
+			attempts = fmt.Sprintf(", %d attempts", result.Attempts)
+		}
+
+		if result.Success {
+			passed = append(passed, projectKey)
+			durations[projectKey] = duration
+		} else {
+			failed = append(failed, projectKey)
+		}
+
+		slow := ""
+		if result.SlowWarning {
+			slow = " " + colorWarning("⚠️ SLOW")
+			slowProjects = append(slowProjects, project.Config.Name)
+		}
+
+		if result.Success {
+			fmt.Fprintf(out, " %s (%v%s)%s\n", colorSuccess("✅ SUCCESS"), duration.Truncate(time.Millisecond), attempts, slow)
+			if len(result.Artifacts) > 0 {
+				fmt.Fprintf(out, "  📦 Recorded %d artifact(s)\n", len(result.Artifacts))
+			}
+			if result.Service != nil {
+				fmt.Fprintf(out, "  🔌 Running in the background (pid %d, log: %s)\n", result.Service.PID, result.Service.LogFile)
+			}
+			if history != nil {
+				if average, ok := history.AverageDuration(scriptName, projectKey); ok && average > 0 {
+					if ratio := float64(duration) / float64(average); ratio >= regressionFactor {
+						fmt.Fprintf(out, "  ⚠️  %.1fx slower than average (%v)\n", ratio, average.Truncate(time.Millisecond))
+					}
+				}
+			}
+		} else {
+			fmt.Fprintf(out, " %s (exit %d, %v%s)%s\n", colorFailure("❌ FAILED"), result.ExitCode, duration.Truncate(time.Millisecond), attempts, slow)
+		}
+
+		if verbose || !result.Success {
+			if len(result.Steps) > 0 {
+				printSteps(out, result.Steps)
+			}
+			logLines := maxLogLines
+			if result.Output != "" {
+				fmt.Fprintln(out, "Output:")
+				logLines = printLogLines(out, elideLines(result.Output, outputLines), logLines, maxLogLines > 0)
+			}
+			if result.Error != "" && !result.Success {
+				fmt.Fprintln(out, "Error:")
+				printLogLines(out, elideLines(result.Error, outputLines), logLines, maxLogLines > 0)
+			}
+		}
+		fmt.Fprintln(out)
+
+		if !result.Success {
+			failedSet[projectKey] = true
+			if failedOn == "" {
+				failedOn = project.Config.Name
+			}
+		}
+		return true
+	}
+
+	for _, batch := range batches {
+		if interrupted.Err() != nil {
+			break
+		}
+
+		var runnable []string
+		for _, projectKey := range batch {
+			project := projects[projectKey]
+			if blocker := failedDependency(dependencies[projectKey], failedSet, skipped); blocker != "" {
+				skipped[projectKey] = blocker
+				skippedOrder = append(skippedOrder, projectKey)
+				runs = append(runs, projectRun{Key: projectKey, SkippedBecause: blocker})
+				fmt.Fprintf(out, "[%d/%d] Skipping %s (%s)... %s (dependency %s did not succeed)\n\n", position[projectKey], len(targetProjects), project.Config.Name, project.Config.Namespace, colorWarning("⏭️  SKIPPED"), blocker)
+				if events != nil {
+					events.skip(projectKey, blocker)
+				}
+				continue
+			}
+			runnable = append(runnable, projectKey)
+		}
+
+		if len(runnable) == 1 {
+			projectKey := runnable[0]
+			project := projects[projectKey]
+			fmt.Fprintf(out, "[%d/%d] Running on %s (%s)...", position[projectKey], len(targetProjects), project.Config.Name, project.Config.Namespace)
+			if interactive {
+				fmt.Fprintln(out)
+			}
+
+			var slowTimer *time.Timer
+			if maxRuntime > 0 {
+				name := project.Config.Name
+				slowTimer = time.AfterFunc(maxRuntime, func() {
+					fmt.Fprintf(os.Stderr, "\n⚠️  %s is still running after %v\n", name, maxRuntime)
+				})
+			}
+
+			start := time.Now()
+			result, err := runner.ExecuteScript(ctx, projectKey, scriptName)
+			duration := time.Since(start)
+
+			if slowTimer != nil {
+				slowTimer.Stop()
+			}
+
+			if err != nil {
+				fmt.Fprintf(out, " %s\n", colorFailure("❌ ERROR"))
+				emitSummary(false)
+				return fmt.Errorf("execution failed: %w", err)
+			}
+
+			if !report(projectKey, result, duration) {
+				break
+			}
+		} else if len(runnable) > 1 {
+			fmt.Fprintf(out, "Running %d project(s) in parallel...\n\n", len(runnable))
+			results, err := runner.ExecuteScriptsParallel(ctx, [][]string{runnable}, scriptName, runtime.NumCPU())
+			if err != nil && !errors.Is(err, ctx.Err()) {
+				emitSummary(false)
+				return fmt.Errorf("execution failed: %w", err)
+			}
+
+			// Results come in the order of runnable; projects never started are missing
+			for _, result := range results {
+				project := projects[result.ProjectKey]
+				fmt.Fprintf(out, "[%d/%d] %s (%s)...", position[result.ProjectKey], len(targetProjects), project.Config.Name, project.Config.Namespace)
+				report(result.ProjectKey, result, result.Duration)
+			}
+		}
+
+		if failedOn != "" && !continueOnError {
+			if showSummary {
+				printRunSummary(out, runs, len(targetProjects), summaryThreshold)
+			}
+			emitSummary(false)
+			return fmt.Errorf("script failed on %s", failedOn)
+		}
+	}
+
+	if interrupted.Err() != nil {
+		fmt.Fprintf(out, "%s after %d of %d project(s)\n\n", colorWarning("⏹️  Interrupted"), len(runs), len(targetProjects))
+		if c.Bool("summary-on-signal") && !c.Bool("no-summary") {
+			printRunSummary(out, runs, len(targetProjects), summaryThreshold)
+		}
+		emitSummary(true)
+		if len(inFlight) > 0 {
+			return fmt.Errorf("script '%s' interrupted while running %s", scriptName, strings.Join(inFlight, ", "))
+		}
+		return fmt.Errorf("script '%s' interrupted", scriptName)
+	}
+
+	if showSummary {
+		printRunSummary(out, runs, len(targetProjects), summaryThreshold)
+	}
+	emitSummary(false)
+
+	if len(failed) > 0 {
+		fmt.Fprintln(out, colorFailure(fmt.Sprintf("❌ Script '%s' failed on %d project(s):", scriptName, len(failed))))
+		for _, key := range failed {
+			fmt.Fprintf(out, "  - %s\n", key)
+		}
+		if len(skippedOrder) > 0 {
+			fmt.Fprintln(out, colorWarning(fmt.Sprintf("⏭️  Skipped %d project(s) because a dependency did not succeed:", len(skippedOrder))))
+			for _, key := range skippedOrder {
+				fmt.Fprintf(out, "  - %s (depends on %s)\n", key, skipped[key])
+			}
+		}
+	} else {
+		fmt.Fprintln(out, colorSuccess(fmt.Sprintf("✅ Script '%s' completed successfully on all projects!", scriptName)))
+	}
+	if len(slowProjects) > 0 {
+		fmt.Fprintf(out, "⚠️  %d project(s) exceeded the max runtime of %v: %s\n", len(slowProjects), maxRuntime, strings.Join(slowProjects, ", "))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("script failed on %d project(s)", len(failed))
+	}
+	return nil
+}
+
+// printDryRun shows what each project would run: the command with its
+// variables substituted, the same command with the environment variables it
+// references expanded, its working directory, and the variables duck sets on
+// top of the inherited environment
+func printDryRun(out io.Writer, runner *executor.Executor, scriptName string, targetProjects []string, projects map[string]*config.AppProject) error {
+	fmt.Fprintf(out, "Would run script '%s' on the following projects:\n", scriptName)
+	for _, key := range targetProjects {
+		project := projects[key]
+		fmt.Fprintf(out, "  - %s (%s)\n", project.Config.Name, project.Config.Namespace)
+		if !project.Config.ScriptEnabled(scriptName) {
+			fmt.Fprintln(out, "    (script disabled for this project)")
+			continue
+		}
+
+		command, workingDir, env, err := runner.ResolveCommand(key, scriptName)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "    Working dir: %s\n", workingDir)
+		fmt.Fprintf(out, "    Command: %s\n", command)
+		if expanded := expandEnvironment(command, env); expanded != command {
+			fmt.Fprintf(out, "    Expanded: %s\n", expanded)
+		}
+
+		var set []string
+		for _, entry := range env {
+			name, value, _ := strings.Cut(entry, "=")
+			if inherited, ok := os.LookupEnv(name); !ok || inherited != value {
+				set = append(set, entry)
+			}
+		}
+		if len(set) > 0 {
+			fmt.Fprintln(out, "    Environment:")
+			for _, entry := range set {
+				fmt.Fprintf(out, "      %s\n", entry)
+			}
+		}
+	}
+
+	return nil
+}
+
+// expandEnvironment replaces the $NAME and ${NAME} references in command with
+// their values in env, the KEY=value entries the command runs with, the way a
+// POSIX shell would. Text in single quotes or after a backslash, and variables
+// env does not set (which the command may set itself), are left as written.
+func expandEnvironment(command string, env []string) string {
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		values[name] = value
+	}
+
+	var out strings.Builder
+	singleQuoted, doubleQuoted := false, false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == '\'' && !doubleQuoted:
+			singleQuoted = !singleQuoted
+		case singleQuoted:
+		case c == '"':
+			doubleQuoted = !doubleQuoted
+		case c == '\\' && i+1 < len(command):
+			out.WriteByte(c)
+			i++
+			c = command[i]
+		case c == '$':
+			name, end := variableName(command, i+1)
+			if value, exists := values[name]; exists && name != "" {
+				out.WriteString(value)
+				i = end - 1
+				continue
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// variableName parses the variable name of a $ reference starting at start,
+// either NAME or {NAME}, and returns it with the index just past the reference.
+// The name is empty when there is no valid reference.
+func variableName(command string, start int) (string, int) {
+	if start < len(command) && command[start] == '{' {
+		end := strings.IndexByte(command[start:], '}')
+		if end < 0 {
+			return "", start
+		}
+		return command[start+1 : start+end], start + end + 1
+	}
+
+	end := start
+	for end < len(command) {
+		c := command[end]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || end > start && c >= '0' && c <= '9') {
+			break
+		}
+		end++
+	}
+	return command[start:end], end
+}
+
+// printEnvironment shows the complete environment the script would run with
+// in each project, for debugging which value of a variable wins
+func printEnvironment(out io.Writer, runner *executor.Executor, scriptName string, targetProjects []string, projects map[string]*config.AppProject) error {
+	for i, key := range targetProjects {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "# %s\n", projects[key].Config.Name)
+
+		_, _, env, err := runner.ResolveCommand(key, scriptName)
+		if err != nil {
+			return err
+		}
+		for _, entry := range env {
+			fmt.Fprintln(out, entry)
+		}
+	}
+
+	return nil
+}
+
+// interruptGracePeriod is how long a project in flight may keep running after
+// a run with --summary-on-signal is interrupted
+const interruptGracePeriod = 5 * time.Second
+
+// printSteps lists the steps of a script run and how each one ended
+func printSteps(w io.Writer, steps []executor.StepResult) {
+	fmt.Fprintln(w, "Steps:")
+	for i, step := range steps {
+		if step.Success {
+			fmt.Fprintf(w, "  %s %d. %s (%v)\n", colorSuccess("✅"), i+1, step.Command, step.Duration.Truncate(time.Millisecond))
+		} else {
+			fmt.Fprintf(w, "  %s %d. %s (exit %d, %v)\n", colorFailure("❌"), i+1, step.Command, step.ExitCode, step.Duration.Truncate(time.Millisecond))
+		}
+	}
+}
+
+// elideLines splits captured output into lines, keeping only the first and
+// last of them around a marker when there are more than limit (0 = no limit).
+// The result keeps the full output; this only shortens what is printed.
+func elideLines(output string, limit int) []string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if limit == 0 || len(lines) <= limit {
+		return lines
+	}
+
+	head := limit / 2
+	tail := limit - head
+	elided := append([]string{}, lines[:head]...)
+	elided = append(elided, fmt.Sprintf("... (%d lines omitted) ...", len(lines)-limit))
+	return append(elided, lines[len(lines)-tail:]...)
+}
+
+// printLogLines prints the lines of captured output. When limited, at most
+// budget lines are printed and the rest are counted in a truncation marker.
+// It returns the budget left for the project's next stream.
+func printLogLines(w io.Writer, lines []string, budget int, limited bool) int {
+	if limited && len(lines) > budget {
+		for _, line := range lines[:budget] {
+			fmt.Fprintf(w, "  │ %s\n", line)
+		}
+		fmt.Fprintf(w, "  │ ...(truncated %d more lines)\n", len(lines)-budget)
+		return 0
+	}
+
+	for _, line := range lines {
+		fmt.Fprintf(w, "  │ %s\n", line)
+	}
+	return budget - len(lines)
+}
+
+// parallelBatches groups targetProjects by dependency level, keeping their
+// order within a level, so that a batch only depends on earlier batches (on
+// later ones with reverse)
+func parallelBatches(projects map[string]*config.AppProject, targetProjects []string, reverse bool) ([][]string, error) {
+	levels, err := resolver.New(projects).ResolveExecutionLevels()
+	if err != nil {
+		return nil, fmt.Errorf("--parallel cannot order the projects: %w", err)
+	}
+
+	levelOf := make(map[string]int)
+	for i, level := range levels {
+		for _, key := range level {
+			levelOf[key] = i
+		}
+	}
+
+	byLevel := make([][]string, len(levels))
+	for _, key := range targetProjects {
+		byLevel[levelOf[key]] = append(byLevel[levelOf[key]], key)
+	}
+
+	var batches [][]string
+	for _, batch := range byLevel {
+		if len(batch) > 0 {
+			batches = append(batches, batch)
+		}
+	}
+	if reverse {
+		slices.Reverse(batches)
+	}
+	return batches, nil
+}
+
+// failedDependency returns the first of dependencies that failed or was
+// skipped in the current run, or "" when there is none
+func failedDependency(dependencies []string, failed map[string]bool, skipped map[string]string) string {
+	for _, dep := range dependencies {
+		if _, isSkipped := skipped[dep]; failed[dep] || isSkipped {
+			return dep
+		}
+	}
+	return ""
+}
+
+// dependencyMap returns the dependencies of each project as the resolver sees
+// them, falling back to the configured dependencies when the resolver rejects
+// the graph (for example because of an unknown dependency)
+func dependencyMap(projects map[string]*config.AppProject) map[string][]string {
+	if resolution, err := resolver.New(projects).ResolveBestEffortOrder(); err == nil {
+		return resolution.Dependencies
+	}
+
+	dependencies := make(map[string][]string, len(projects))
+	for key, project := range projects {
+		dependencies[key] = project.Config.Dependencies
+	}
+	return dependencies
+}
+
+// outputShapingFlags are the run flags that process a script's captured output,
+// which an interactive script does not have
+var outputShapingFlags = []string{"parallel", "combine-output", "max-log-lines-per-project", "output-lines"}
+
+// autoInteractive reports whether a run without --interactive should still be
+// interactive: a single project on a terminal is, unless --interactive=false,
+// --events or an output-shaping flag is given, or the script is a service.
+func autoInteractive(c *cli.Context, projectCount int, script config.Script, terminal bool) bool {
+	if !terminal || projectCount != 1 || c.IsSet("interactive") || c.Bool("events") || script.ReadyWhen != "" {
+		return false
+	}
+	for _, flag := range outputShapingFlags {
+		if c.IsSet(flag) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkServiceWritable blocks a readyWhen script with --read-only, since
+// services log to files in the workspace
+func checkServiceWritable(script config.Script) error {
+	if script.ReadyWhen == "" {
+		return nil
+	}
+	return checkWritable(executor.ServicesDir)
+}
+
+// stopServices stops the services the run left in the background
+func stopServices(out io.Writer, runner *executor.Executor) {
+	services := runner.Services()
+	if len(services) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "🛑 Stopping %d service(s)...\n", len(services))
+	if err := runner.StopServices(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// printServices lists the services left running with --keep-services
+func printServices(out io.Writer, runner *executor.Executor) {
+	services := runner.Services()
+	if len(services) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "🔌 %d service(s) still running:\n", len(services))
+	for _, service := range services {
+		fmt.Fprintf(out, "  - %s %s (pid %d, log: %s)\n", service.ProjectKey, service.Script, service.PID, service.LogFile)
+	}
+}
+
+// selectTargetProjects resolves the run selection flags into an ordered list of project keys
+func selectTargetProjects(c *cli.Context, projects map[string]*config.AppProject) ([]string, error) {
 	var targetProjects []string
 
-	if c.Bool("all") {
+	if c.Bool("reverse") && !c.Bool("all") {
+		return nil, fmt.Errorf("--reverse can only be used with --all")
+	}
+
+	if c.Bool("warn-on-cycle") && c.Bool("abort-on-cycle") {
+		return nil, fmt.Errorf("--warn-on-cycle and --abort-on-cycle cannot be used together")
+	}
+	if c.Bool("warn-on-cycle") && !c.Bool("all") {
+		return nil, fmt.Errorf("--warn-on-cycle can only be used with --all")
+	}
+
+	// Like the other selectors, the listed projects of --projects-json and
+	// --projects-from-file and the failures of --only-failed-last-run can be
+	// narrowed by --filter and --since and extended by --with-deps
+	if raw := c.String("projects-json"); raw != "" {
+		selection, err := projectsFromJSON(raw, c.String("script"), projects)
+		if err != nil {
+			return nil, err
+		}
+		targetProjects = selection
+	} else if path := c.String("projects-from-file"); path != "" {
+		selection, err := readProjectSelection(path, projects)
+		if err != nil {
+			return nil, err
+		}
+		targetProjects = selection
+	} else if c.Bool("only-failed-last-run") {
+		failed, err := failedLastRun(c.String("script"), projects)
+		if err != nil {
+			return nil, err
+		}
+		targetProjects = failed
+	} else if c.Bool("all") {
 		resolver := resolver.New(projects)
-		resolution, err := resolver.ResolveExecutionOrder()
+		resolve := resolver.ResolveExecutionOrder
+		if c.Bool("warn-on-cycle") {
+			resolve = resolver.ResolveBestEffortOrder
+		}
+		if c.Bool("reverse") {
+			resolve = resolver.ResolveTeardownOrder
+			if c.Bool("warn-on-cycle") {
+				resolve = resolver.ResolveBestEffortTeardownOrder
+			}
+		}
+		resolution, err := resolve()
 		if err != nil {
-			return fmt.Errorf("failed to resolve dependencies: %w", err)
+			return nil, fmt.Errorf("failed to resolve dependencies: %w", err)
+		}
+		if len(resolution.Cyclic) > 0 {
+			fmt.Fprintf(os.Stderr, "⚠️  WARNING: circular dependency detected; %d project(s) will run in arbitrary order:\n", len(resolution.Cyclic))
+			for _, key := range resolution.Cyclic {
+				fmt.Fprintf(os.Stderr, "  - %s\n", key)
+			}
 		}
 		targetProjects = resolution.ExecutionOrder
 	} else if projectNames := c.StringSlice("project"); len(projectNames) > 0 {
 		for _, name := range projectNames {
 			// Resolve project name or key to actual project key
-			projectKey, exists := ResolveProjectKey(name, projects)
-			if !exists {
-				return fmt.Errorf("project '%s' not found", name)
+			projectKey, err := ResolveProjectKey(name, projects)
+			if err != nil {
+				return nil, err
 			}
 			targetProjects = append(targetProjects, projectKey)
 		}
@@ -131,114 +1032,244 @@ func RunScript(c *cli.Context) error {
 			targetProjects = append(targetProjects, key)
 		}
 		sort.Strings(targetProjects)
+	} else if c.String("filter") != "" || c.String("since") != "" {
+		for key := range projects {
+			targetProjects = append(targetProjects, key)
+		}
+		sort.Strings(targetProjects)
 	} else {
-		return fmt.Errorf("must specify --all, --project, --namespace, or --tag")
+		return nil, fmt.Errorf("must specify --all, --project, --projects-from-file, --projects-json, --only-failed-last-run, --namespace, --tag, --filter, or --since")
 	}
 
-	if len(targetProjects) == 0 {
-		fmt.Println("No projects match the selection criteria.")
-		return nil
+	if expression := c.String("filter"); expression != "" {
+		match, err := compileFilter(expression, projects)
+		if err != nil {
+			return nil, err
+		}
+
+		var filtered []string
+		for _, key := range targetProjects {
+			if match(key, projects[key]) {
+				filtered = append(filtered, key)
+			}
+		}
+		targetProjects = filtered
 	}
 
-	if c.Bool("dry-run") {
-		fmt.Printf("Would run script '%s' on the following projects:\n", scriptName)
+	if since := c.String("since"); since != "" {
+		selected := make(map[string]*config.AppProject, len(targetProjects))
 		for _, key := range targetProjects {
-			project := projects[key]
-			fmt.Printf("  - %s (%s)\n", project.Config.Name, project.Config.Namespace)
+			selected[key] = projects[key]
 		}
-		return nil
+		changed, err := filterProjectsChangedSince(selected, since)
+		if err != nil {
+			return nil, err
+		}
+
+		var filtered []string
+		for _, key := range targetProjects {
+			if _, exists := changed[key]; exists {
+				filtered = append(filtered, key)
+			}
+		}
+		targetProjects = filtered
 	}
 
-	executor := executor.New(projectConfig, projects)
-	ctx := context.Background()
+	if c.Bool("with-deps") && len(targetProjects) > 0 {
+		resolution, err := resolver.New(projects).ResolveForTargets(targetProjects)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve dependencies: %w", err)
+		}
+		targetProjects = resolution.ExecutionOrder
+	}
 
-	verbose := c.Bool("verbose")
+	return targetProjects, nil
+}
 
-	fmt.Printf("Running script '%s' on %d project(s)...\n\n", scriptName, len(targetProjects))
+func ListScripts(c *cli.Context) error {
+	if c.String("project") != "" && c.String("namespace") != "" {
+		return fmt.Errorf("--project and --namespace cannot be used together")
+	}
+	if c.String("project") != "" {
+		return listProjectScripts(c)
+	}
+	if c.String("namespace") != "" {
+		return listNamespaceScripts(c)
+	}
 
-	for i, projectKey := range targetProjects {
-		project := projects[projectKey]
-		fmt.Printf("[%d/%d] Running on %s (%s)...", i+1, len(targetProjects), project.Config.Name, project.Config.Namespace)
+	projectConfig, _, err := LoadProjectData()
+	if err != nil {
+		return err
+	}
 
-		start := time.Now()
-		result, err := executor.ExecuteScript(ctx, projectKey, scriptName)
-		duration := time.Since(start)
+	fmt.Println("Available scripts:")
 
-		if err != nil {
-			fmt.Printf(" ❌ ERROR\n")
-			return fmt.Errorf("execution failed: %w", err)
+	for _, name := range sortedScriptNames(projectConfig) {
+		script := projectConfig.Scripts[name]
+		printScriptLine("  ", name, script, "")
+		if c.Bool("verbose") {
+			printScriptCommands(script)
 		}
+	}
 
-		if result.Success {
-			fmt.Printf(" ✅ SUCCESS (%v)\n", duration.Truncate(time.Millisecond))
-		} else {
-			fmt.Printf(" ❌ FAILED (%v)\n", duration.Truncate(time.Millisecond))
+	return nil
+}
+
+// listProjectScripts lists the scripts enabled for the --project project, with
+// the project's overrides applied, followed by the scripts it disables
+func listProjectScripts(c *cli.Context) error {
+	projectConfig, scanner, err := loadProjectScanner()
+	if err != nil {
+		return err
+	}
+	projects := scanner.GetProjects()
+
+	projectKey, err := ResolveProjectKey(c.String("project"), projects)
+	if err != nil {
+		return err
+	}
+	project := projects[projectKey]
+
+	enabled := make(map[string]bool)
+	for _, name := range scanner.GetAvailableScripts(project) {
+		enabled[name] = true
+	}
+
+	fmt.Printf("Scripts for %s (%s):\n", project.Config.Name, projectKey)
+
+	var disabled []string
+	for _, name := range sortedScriptNames(projectConfig) {
+		if !enabled[name] {
+			disabled = append(disabled, name)
+			continue
 		}
 
-		if verbose || !result.Success {
-			if result.Output != "" {
-				fmt.Println("Output:")
-				lines := strings.Split(strings.TrimSpace(result.Output), "\n")
-				for _, line := range lines {
-					fmt.Printf("  │ %s\n", line)
-				}
-			}
-			if result.Error != "" && !result.Success {
-				fmt.Println("Error:")
-				lines := strings.Split(strings.TrimSpace(result.Error), "\n")
-				for _, line := range lines {
-					fmt.Printf("  │ %s\n", line)
-				}
-			}
+		script := projectConfig.Scripts[name]
+		note := ""
+		if override, exists := project.Config.Scripts[name]; exists && (override.Command != "" || override.WorkingDir != "" || len(override.Environment) > 0) {
+			script = override.Apply(script)
+			note = " (overridden by the project)"
+		}
+		printScriptLine("  ", name, script, note)
+		if c.Bool("verbose") {
+			printScriptCommands(script)
+		}
+	}
+	if len(enabled) == 0 {
+		fmt.Println("  (none)")
+	}
+
+	if len(disabled) > 0 {
+		fmt.Println("Disabled for this project:")
+		for _, name := range disabled {
+			printScriptLine("  ✗ ", name, projectConfig.Scripts[name], "")
+		}
+	}
+
+	return nil
+}
+
+// listNamespaceScripts lists the scripts available to the projects of the
+// --namespace namespace, marking those that only some of them enable
+func listNamespaceScripts(c *cli.Context) error {
+	namespace := c.String("namespace")
+
+	projectConfig, scanner, err := loadProjectScanner()
+	if err != nil {
+		return err
+	}
+
+	var projectKeys []string
+	for key, project := range scanner.GetProjects() {
+		if project.Config.Namespace == namespace {
+			projectKeys = append(projectKeys, key)
+		}
+	}
+	if len(projectKeys) == 0 {
+		return fmt.Errorf("no projects found in namespace '%s'", namespace)
+	}
+	sort.Strings(projectKeys)
+
+	// The projects of the namespace that each script is available to
+	availableIn := make(map[string][]string)
+	for _, key := range projectKeys {
+		project, _ := scanner.GetProject(key)
+		for _, name := range scanner.GetAvailableScripts(project) {
+			availableIn[name] = append(availableIn[name], key)
+		}
+	}
+
+	fmt.Printf("Scripts in namespace '%s' (%d project(s)):\n", namespace, len(projectKeys))
+
+	var unavailable []string
+	for _, name := range sortedScriptNames(projectConfig) {
+		keys := availableIn[name]
+		if len(keys) == 0 {
+			unavailable = append(unavailable, name)
+			continue
 		}
-		fmt.Println()
 
-		if !result.Success {
-			return fmt.Errorf("script failed on %s", project.Config.Name)
+		note := ""
+		if len(keys) < len(projectKeys) {
+			sort.Strings(keys)
+			note = fmt.Sprintf(" (only %d of %d: %s)", len(keys), len(projectKeys), strings.Join(keys, ", "))
+		}
+		script := projectConfig.Scripts[name]
+		printScriptLine("  ", name, script, note)
+		if c.Bool("verbose") {
+			printScriptCommands(script)
 		}
 	}
 
-	fmt.Printf("✅ Script '%s' completed successfully on all projects!\n", scriptName)
-	return nil
-}
-
-func ListScripts(c *cli.Context) error {
-	projectConfig, _, err := LoadProjectData()
-	if err != nil {
-		return err
+	if len(unavailable) > 0 {
+		fmt.Println("Disabled for every project of the namespace:")
+		for _, name := range unavailable {
+			printScriptLine("  ✗ ", name, projectConfig.Scripts[name], "")
+		}
 	}
 
-	fmt.Println("Available scripts:")
+	return nil
+}
 
+// sortedScriptNames returns the names of the scripts of duck.yaml in order
+func sortedScriptNames(projectConfig *config.ProjectConfig) []string {
 	var scriptNames []string
 	for name := range projectConfig.Scripts {
 		scriptNames = append(scriptNames, name)
 	}
 	sort.Strings(scriptNames)
+	return scriptNames
+}
 
-	for _, name := range scriptNames {
-		script := projectConfig.Scripts[name]
-		fmt.Printf("  %s", name)
-		if script.Description != "" {
-			fmt.Printf(" - %s", script.Description)
-		}
-		fmt.Println()
-		if c.Bool("verbose") {
-			fmt.Printf("    Command: %s\n", script.Command)
-		}
+// printScriptLine prints a script's name and description, followed by note
+func printScriptLine(prefix, name string, script config.Script, note string) {
+	fmt.Printf("%s%s", prefix, name)
+	if script.Description != "" {
+		fmt.Printf(" - %s", script.Description)
 	}
+	fmt.Println(note)
+}
 
-	return nil
+// printScriptCommands prints the command, or the steps, a script runs
+func printScriptCommands(script config.Script) {
+	if len(script.Commands) > 0 {
+		fmt.Println("    Commands:")
+		for i, command := range script.Commands {
+			fmt.Printf("      %d. %s\n", i+1, command)
+		}
+	} else {
+		fmt.Printf("    Command: %s\n", script.Command)
+	}
 }
 
 func ConfigFormat(c *cli.Context) error {
-	configPath := "duck.yaml"
+	configPath := globalOptions.ConfigFile
 
 	setFormat := c.String("set")
 
 	if setFormat != "" {
-		if setFormat != "duck" && setFormat != "nx" && setFormat != "all" {
-			return fmt.Errorf("invalid format: must be 'duck', 'nx', or 'all'")
+		if !config.ProjectConfigFormat(setFormat).IsValid() {
+			return fmt.Errorf("invalid format: %w", config.InvalidFormatError(setFormat))
 		}
 
 		if err := UpdateProjectConfigFormat(configPath, setFormat); err != nil {
@@ -250,10 +1281,16 @@ func ConfigFormat(c *cli.Context) error {
 		if setFormat == "nx" {
 			fmt.Println("\nNote: Duck will now look for 'project.json' files instead of 'app.yaml'")
 			fmt.Println("   All Nx targets will be automatically available as scripts")
+		} else if setFormat == "toml" {
+			fmt.Println("\nNote: Duck will now look for 'project.toml' files instead of 'app.yaml'")
 		} else if setFormat == "all" {
 			fmt.Println("\nNote: Duck will now look for both 'app.yaml' AND 'project.json' files")
 			fmt.Println("   If both exist in the same directory, 'app.yaml' takes precedence")
 			fmt.Println("   All Nx targets will be automatically available as scripts")
+		} else if setFormat == "all+toml" {
+			fmt.Println("\nNote: Duck will now look for 'app.yaml', 'project.toml' AND 'project.json' files")
+			fmt.Println("   If several exist in the same directory, 'app.yaml' takes precedence, then 'project.toml'")
+			fmt.Println("   All Nx targets will be automatically available as scripts")
 		} else {
 			fmt.Println("\nNote: Duck will now look for 'app.yaml' files")
 		}
@@ -272,25 +1309,145 @@ func ConfigFormat(c *cli.Context) error {
 		fmt.Println("Using Duck's app.yaml format")
 	} else if projectConfig.ProjectConfigFormat == "nx" {
 		fmt.Println("Using Nx's project.json format")
+	} else if projectConfig.ProjectConfigFormat == "toml" {
+		fmt.Println("Using the project.toml format")
 	} else if projectConfig.ProjectConfigFormat == "all" {
 		fmt.Println("Using both Duck's app.yaml and Nx's project.json formats")
 		fmt.Println("(app.yaml takes precedence when both exist in same directory)")
+	} else if projectConfig.ProjectConfigFormat == "all+toml" {
+		fmt.Println("Using Duck's app.yaml, project.toml and Nx's project.json formats")
+		fmt.Println("(app.yaml, then project.toml, takes precedence when several exist in same directory)")
+	}
+
+	return nil
+}
+
+const initConfigTemplate = `---
+# Duck Monorepo Configuration
+
+# Directory where Duck will scan for applications
+targetDirectory: "%s"
+
+# Project configuration format: "duck", "nx", "toml", "all", or "all+toml"
+# - duck: uses app.yaml files
+# - nx: uses project.json files (compatible with Nx monorepo)
+# - toml: uses project.toml files
+# - all: uses app.yaml and project.json (app.yaml takes precedence)
+# - all+toml: uses all three (app.yaml, then project.toml, takes precedence)
+projectConfigFormat: "%s"
+
+# Global scripts that can be run on projects
+scripts:
+  build:
+    command: "go build ."
+    description: "Build the Go application"
+    workingDir: "{projectRoot}"
+
+  test:
+    command: "go test -v ./..."
+    description: "Run tests with verbose output"
+    workingDir: "{projectRoot}"
+`
+
+func InitWorkspace(c *cli.Context) error {
+	configPath := "duck.yaml"
+
+	if err := checkWritable(configPath); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(configPath); err == nil && !c.Bool("force") {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", configPath)
+	}
+
+	format := c.String("format")
+	if !config.ProjectConfigFormat(format).IsValid() {
+		return fmt.Errorf("invalid format: %w", config.InvalidFormatError(format))
+	}
+
+	targetDirectory := c.String("target-directory")
+	if targetDirectory == "" {
+		targetDirectory = "."
+		if info, err := os.Stat("apps"); err == nil && info.IsDir() {
+			targetDirectory = "./apps"
+		}
+	}
+
+	content := fmt.Sprintf(initConfigTemplate, targetDirectory, format)
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", configPath, err)
+	}
+
+	fmt.Printf("Created %s (targetDirectory: %s, format: %s)\n", configPath, targetDirectory, format)
+
+	_, projects, err := LoadProjectData()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Discovered %d project(s)\n", len(projects))
+	return nil
+}
+
+func ClearCache(c *cli.Context) error {
+	if err := checkWritable("the scan cache"); err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	if err := scanner.ClearCache(cwd); err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(cwd, scanner.CacheDir, goscan.GraphCacheFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove dependency graph cache: %w", err)
 	}
 
+	fmt.Println("Scan cache and dependency graph cache cleared")
 	return nil
 }
 
+// workspaceFlag returns the absolute directory of a command's --workspace
+// flag. A relative path is resolved from the directory duck was started in;
+// without the flag it is the workspace root duck runs in.
+func workspaceFlag(c *cli.Context) (string, error) {
+	if !c.IsSet("workspace") {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get working directory: %w", err)
+		}
+		return cwd, nil
+	}
+
+	dir := c.String("workspace")
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(globalOptions.InvocationDir, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
 func AnalyzeDependencies(c *cli.Context) error {
-	workspaceRoot := c.String("workspace")
-	if workspaceRoot == "" {
-		workspaceRoot = "."
+	if c.Bool("sync") {
+		if err := checkWritable("project config files (--sync)"); err != nil {
+			return err
+		}
+	}
+
+	absWorkspaceRoot, err := workspaceFlag(c)
+	if err != nil {
+		return err
 	}
 
-	// Convert workspace root to absolute path
-	absWorkspaceRoot, err := filepath.Abs(workspaceRoot)
+	paths, err := newPathFormatter(c.String("path-style"))
 	if err != nil {
-		return fmt.Errorf("failed to get absolute workspace path: %w", err)
+		return err
 	}
+	paths.workspaceRoot = absWorkspaceRoot
 
 	// Change to workspace directory to load configuration
 	originalCwd, err := os.Getwd()
@@ -304,37 +1461,29 @@ func AnalyzeDependencies(c *cli.Context) error {
 	defer os.Chdir(originalCwd)
 
 	// Load projects from configuration
-	_, allProjects, err := LoadProjectData()
+	projectConfig, allProjects, err := LoadProjectData()
 	if err != nil {
 		return fmt.Errorf("failed to load project data: %w", err)
 	}
 
-	// Build a set of all local packages
-	localPackages := make(map[string]bool)
-	for _, project := range allProjects {
-		// Extract module name from go.mod
-		goModPath := filepath.Join(project.Path, "go.mod")
-		if data, err := os.ReadFile(goModPath); err == nil {
-			lines := strings.Split(string(data), "\n")
-			for _, line := range lines {
-				trimmed := strings.TrimSpace(line)
-				if strings.HasPrefix(trimmed, "module ") {
-					moduleName := strings.TrimSpace(strings.TrimPrefix(trimmed, "module "))
-					localPackages[moduleName] = true
-					break
-				}
-			}
+	localPackages := localGoModules(allProjects)
+	projectDirs := workspaceProjectDirs(absWorkspaceRoot, allProjects)
+
+	// A go.work at the workspace root is the canonical list of internal modules
+	workModules, err := goWorkModules(absWorkspaceRoot, allProjects)
+	if err != nil {
+		return err
+	}
+	if workModules != nil {
+		localPackages = make(map[string]bool, len(workModules))
+		for module := range workModules {
+			localPackages[module] = true
 		}
 	}
 
-	// Extract project paths from loaded projects
-	projectDirs := make([]string, 0)
-	for _, project := range allProjects {
-		// Get relative path from workspace root to project
-		relPath, err := filepath.Rel(absWorkspaceRoot, project.Path)
-		if err == nil {
-			projectDirs = append(projectDirs, relPath)
-		}
+	// Projects sharing a module are internal packages of it
+	for packagePath := range goPackagePaths(allProjects) {
+		localPackages[packagePath] = true
 	}
 
 	if len(projectDirs) == 0 {
@@ -342,17 +1491,55 @@ func AnalyzeDependencies(c *cli.Context) error {
 		return nil
 	}
 
-	fmt.Println("> Scanning Go projects for dependencies...\n")
+	// Only re-analyze projects with changes since the given commit
+	if since := c.String("since"); since != "" {
+		files, err := gitChangedFiles(absWorkspaceRoot, since)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot list changes since %s, analyzing all projects: %v\n", since, err)
+		} else {
+			projectDirs = changedProjectDirs(absWorkspaceRoot, projectDirs, files)
+			if len(projectDirs) == 0 {
+				fmt.Printf("No projects changed since %s.\n", since)
+				return nil
+			}
+		}
+	}
+
+	jsonOutput := c.Bool("json")
+	edgesOutput := c.Bool("edges")
+	if jsonOutput && edgesOutput {
+		return fmt.Errorf("--json and --edges cannot be used together")
+	}
+	if !jsonOutput && !edgesOutput {
+		fmt.Print("> Scanning projects for dependencies...\n\n")
+	}
 
+	// Every registered language scanner gets the projects it can handle
+	localPaths := make([]string, 0, len(localPackages))
+	for path := range localPackages {
+		localPaths = append(localPaths, path)
+	}
 	builder := goscan.NewGraphBuilder()
-	graph, err := builder.BuildGraph(absWorkspaceRoot, projectDirs)
+	builder.SetIgnore(projectConfig.Ignore)
+	builder.SetLocalPackages(localPaths)
+	builder.RegisterScanner(jsscan.NewJsScanner())
+	builder.SetCacheReadOnly(globalOptions.ReadOnly)
+
+	// Projects are reported by their path relative to the workspace root
+	sort.Strings(projectDirs)
+	var graph *dependencyscanner.DependencyGraph
+	if globalOptions.NoCache || c.Bool("no-cache") {
+		graph, err = builder.BuildGraph(absWorkspaceRoot, projectDirs)
+	} else {
+		graph, err = builder.BuildGraphCached(absWorkspaceRoot, projectDirs, filepath.Join(absWorkspaceRoot, scanner.CacheDir))
+	}
 	if err != nil {
 		return fmt.Errorf("failed to build dependency graph: %w", err)
 	}
 
 	projects := graph.GetProjectsWithDependencies()
 	if len(projects) == 0 {
-		fmt.Println("No Go projects found.")
+		fmt.Println("No projects with a supported language found.")
 		return nil
 	}
 
@@ -361,13 +1548,45 @@ func AnalyzeDependencies(c *cli.Context) error {
 		return projects[i].ProjectPath < projects[j].ProjectPath
 	})
 
+	if c.Bool("conflicts") {
+		return printVersionConflicts(graph.FindVersionConflicts(), localPackages, jsonOutput, paths)
+	}
+
+	if c.Bool("licenses") {
+		return printLicenses(projects, localPackages, jsonOutput, paths)
+	}
+
+	if c.Bool("updates") {
+		return printUpdates(projects, localPackages, jsonOutput, paths)
+	}
+
+	if edgesOutput {
+		printDependencyEdges(projects, localPackages, allProjects, workModules, paths)
+		return nil
+	}
+
+	if jsonOutput {
+		if c.Bool("version-detail") {
+			for _, project := range projects {
+				if project.Language != "go" {
+					continue
+				}
+				projectPath := filepath.Join(absWorkspaceRoot, project.ProjectPath)
+				if err := goscan.AnnotateIndirectVia(project, projectPath); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: no version detail for %s: %v\n", project.ProjectPath, err)
+				}
+			}
+		}
+		return printDependenciesJSON(projects, localPackages, allProjects, workModules, paths)
+	}
+
 	verbose := c.Bool("verbose")
 	showIndirect := c.Bool("show-indirect")
 
-	fmt.Printf("Found %d Go projects:\n\n", len(projects))
+	fmt.Printf("Found %d projects:\n\n", len(projects))
 
 	for _, project := range projects {
-		fmt.Printf("%s\n", project.ProjectPath)
+		fmt.Printf("%s\n", paths.FormatKey(project.ProjectPath))
 
 		// Filter to only internal dependencies
 		var internalDeps []interface{}
@@ -431,9 +1650,11 @@ func AnalyzeDependencies(c *cli.Context) error {
 				}
 
 				// Map module name to project path for display
-				projectPath := mapGoModuleToProjectKey(dep.Target, allProjects)
+				projectPath := dependencyProjectKey(dep, allProjects, workModules)
 				if projectPath == "" {
 					projectPath = dep.Target // Fallback to module name if mapping fails
+				} else {
+					projectPath = paths.FormatKey(projectPath)
 				}
 
 				fmt.Printf("     %s %s", marker, projectPath)
@@ -465,19 +1686,21 @@ func AnalyzeDependencies(c *cli.Context) error {
 
 	// Show which projects depend on which packages (only internal)
 	for pkg := range localPackages {
-		dependents := builder.FindProjectDependencies(graph, pkg)
+		dependents := graph.FindDependents(pkg)
 		if len(dependents) > 0 {
 			// Map module name to project path
-			pkgPath := mapGoModuleToProjectKey(pkg, allProjects)
+			pkgPath := dependencyProjectKey(dependencyscanner.Dependency{Target: pkg}, allProjects, workModules)
 			if pkgPath == "" {
 				pkgPath = pkg // Fallback
+			} else {
+				pkgPath = paths.FormatKey(pkgPath)
 			}
 
 			// Map dependent module names to project paths too
 			var mappedDependents []string
 			for _, dep := range dependents {
 				// dep might be a module name or project path, try to map it
-				depPath := dep // dep is the project path from graph (already relative)
+				depPath := paths.FormatKey(dep) // dep is the project path from graph (already relative)
 				mappedDependents = append(mappedDependents, depPath)
 			}
 
@@ -505,14 +1728,14 @@ func AnalyzeDependencies(c *cli.Context) error {
 
 	// Sync dependencies if flag is set
 	if c.Bool("sync") {
-		fmt.Println("\nSyncing dependencies to configuration files...\n")
+		fmt.Print("\nSyncing dependencies to configuration files...\n\n")
 
 		for _, project := range projects {
 			if len(project.Dependencies) == 0 {
 				continue
 			}
 
-			fmt.Printf("- %s\n", project.ProjectPath)
+			fmt.Printf("- %s\n", paths.FormatKey(project.ProjectPath))
 
 			// Convert Go module dependencies to project keys (only internal)
 			var projectKeys []string
@@ -527,7 +1750,7 @@ func AnalyzeDependencies(c *cli.Context) error {
 				}
 
 				// Map Go module path to project key
-				projectKey := mapGoModuleToProjectKey(dep.Target, allProjects)
+				projectKey := dependencyProjectKey(dep, allProjects, workModules)
 				if projectKey != "" {
 					projectKeys = append(projectKeys, projectKey)
 					if verbose {
@@ -542,7 +1765,7 @@ func AnalyzeDependencies(c *cli.Context) error {
 
 			if len(projectKeys) > 0 {
 				projectPath := filepath.Join(absWorkspaceRoot, project.ProjectPath)
-				if err := syncDependenciesToConfig(projectPath, projectKeys); err != nil {
+				if err := syncDependenciesToConfig(projectPath, projectKeys, projectConfig.AppConfigFileNames()); err != nil {
 					fmt.Printf("    Error: %v\n", err)
 				}
 			} else {
@@ -557,6 +1780,95 @@ func AnalyzeDependencies(c *cli.Context) error {
 	return nil
 }
 
+// localGoModules returns the module names declared in the go.mod of each project
+func localGoModules(allProjects map[string]*config.AppProject) map[string]bool {
+	localPackages := make(map[string]bool)
+	for _, project := range allProjects {
+		if moduleName, err := goscan.ReadModulePath(filepath.Join(project.Path, "go.mod")); err == nil {
+			localPackages[moduleName] = true
+		}
+	}
+	return localPackages
+}
+
+// goPackagePaths maps the import paths of the projects without a go.mod of
+// their own, which share the module of a parent directory, to their keys
+func goPackagePaths(allProjects map[string]*config.AppProject) map[string]string {
+	packagePaths := make(map[string]string)
+	for projectKey, project := range allProjects {
+		if _, err := os.Stat(filepath.Join(project.Path, "go.mod")); err == nil {
+			continue
+		}
+		if packagePath, err := goscan.PackagePath(project.Path); err == nil {
+			packagePaths[packagePath] = projectKey
+		}
+	}
+	return packagePaths
+}
+
+// workspaceProjectDirs returns the project directories relative to the workspace root
+func workspaceProjectDirs(absWorkspaceRoot string, allProjects map[string]*config.AppProject) []string {
+	projectDirs := make([]string, 0)
+	for _, project := range allProjects {
+		// Get relative path from workspace root to project
+		relPath, err := filepath.Rel(absWorkspaceRoot, project.Path)
+		if err == nil {
+			projectDirs = append(projectDirs, relPath)
+		}
+	}
+	return projectDirs
+}
+
+// dependencyProjectKey maps a Go dependency to the key of the project providing
+// it. Modules listed in go.work (workModules, may be nil) are resolved first, then
+// the directory of a local replace directive, then the module path heuristics.
+func dependencyProjectKey(dep dependencyscanner.Dependency, allProjects map[string]*config.AppProject, workModules map[string]string) string {
+	if projectKey := workModules[dep.Target]; projectKey != "" {
+		return projectKey
+	}
+
+	if dep.LocalPath != "" {
+		if projectKey := projectKeyForDir(dep.LocalPath, allProjects); projectKey != "" {
+			return projectKey
+		}
+	}
+
+	return mapGoModuleToProjectKey(dep.Target, allProjects)
+}
+
+// projectKeyForDir returns the key of the project located in dir, if any
+func projectKeyForDir(dir string, allProjects map[string]*config.AppProject) string {
+	dir = canonicalPath(dir)
+	for projectKey, project := range allProjects {
+		if canonicalPath(project.Path) == dir {
+			return projectKey
+		}
+	}
+	return ""
+}
+
+// goWorkModules maps the modules listed in the workspace's go.work to the keys of
+// the projects in their directories ("" for modules that are not projects).
+// It returns nil when there is no go.work.
+func goWorkModules(absWorkspaceRoot string, allProjects map[string]*config.AppProject) (map[string]string, error) {
+	goWorkPath := filepath.Join(absWorkspaceRoot, "go.work")
+	if _, err := os.Stat(goWorkPath); err != nil {
+		return nil, nil
+	}
+
+	modules, err := goscan.ParseGoWork(goWorkPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go.work: %w", err)
+	}
+
+	workModules := make(map[string]string, len(modules))
+	for _, module := range modules {
+		workModules[module.Path] = projectKeyForDir(module.Dir, allProjects)
+	}
+
+	return workModules, nil
+}
+
 // mapGoModuleToProjectKey maps Go module paths to project namespace/name format
 // Returns the relative path from workspace root for clarity
 func mapGoModuleToProjectKey(modulePath string, allProjects map[string]*config.AppProject) string {
@@ -580,10 +1892,14 @@ func mapGoModuleToProjectKey(modulePath string, allProjects map[string]*config.A
 		}
 	}
 
-	// Try to find a direct match first
+	// Try to find a direct match first, including the packages of projects
+	// sharing a module
 	if projectPath, exists := moduleToPath[modulePath]; exists {
 		return projectPath
 	}
+	if projectPath, exists := goPackagePaths(allProjects)[modulePath]; exists {
+		return projectPath
+	}
 
 	// If no direct match, try suffix matching based on the project path structure
 	// For example: module "github.com/org/repo/packages/go/httputils" should match projectKey "packages/go/httputils"
@@ -621,16 +1937,20 @@ func mapGoModuleToProjectKey(modulePath string, allProjects map[string]*config.A
 }
 
 // syncDependenciesToConfig updates app.yaml or project.json with discovered dependencies
-func syncDependenciesToConfig(projectPath string, dependencies []string) error {
-	appYamlPath := filepath.Join(projectPath, "app.yaml")
+func syncDependenciesToConfig(projectPath string, dependencies []string, appYamlNames []string) error {
 	projectJsonPath := filepath.Join(projectPath, "project.json")
 
-	hasAppYaml := false
+	// The first duck-format config file found, app.yaml unless configured otherwise
+	appYamlName := ""
+	for _, name := range appYamlNames {
+		if _, err := os.Stat(filepath.Join(projectPath, name)); err == nil {
+			appYamlName = name
+			break
+		}
+	}
+	hasAppYaml := appYamlName != ""
 	hasProjectJson := false
 
-	if _, err := os.Stat(appYamlPath); err == nil {
-		hasAppYaml = true
-	}
 	if _, err := os.Stat(projectJsonPath); err == nil {
 		hasProjectJson = true
 	}
@@ -639,10 +1959,10 @@ func syncDependenciesToConfig(projectPath string, dependencies []string) error {
 
 	// Update app.yaml if it exists
 	if hasAppYaml {
-		if err := updateAppYamlDependencies(appYamlPath, dependencies); err != nil {
-			errors = append(errors, fmt.Errorf("failed to update app.yaml: %w", err))
+		if err := updateAppYamlDependencies(filepath.Join(projectPath, appYamlName), dependencies); err != nil {
+			errors = append(errors, fmt.Errorf("failed to update %s: %w", appYamlName, err))
 		} else {
-			fmt.Printf("    Updated app.yaml\n")
+			fmt.Printf("    Updated %s\n", appYamlName)
 		}
 	}
 
@@ -660,193 +1980,288 @@ func syncDependenciesToConfig(projectPath string, dependencies []string) error {
 	}
 
 	if !hasAppYaml && !hasProjectJson {
-		return fmt.Errorf("no app.yaml or project.json found")
+		return fmt.Errorf("no %s or project.json found", strings.Join(appYamlNames, ", "))
 	}
 
 	return nil
 }
 
-// updateAppYamlDependencies updates the dependencies in an app.yaml file
+// updateAppYamlDependencies merges dependencies into the dependencies list of
+// an app.yaml file. The file is parsed into yaml.Node to find the list, and only
+// the lines of the list are rewritten, so comments, blank lines and formatting
+// elsewhere in the file are kept.
 func updateAppYamlDependencies(path string, dependencies []string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
 
-	lines := strings.Split(string(data), "\n")
-	var result []string
-	inDependencies := false
-	dependenciesFound := false
-	indentLevel := ""
-	existingDeps := make(map[string]bool)
-
-	// First pass: collect existing dependencies
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
 
-		if strings.HasPrefix(strings.TrimSpace(line), "dependencies:") {
-			dependenciesFound = true
-			inDependencies = true
-			continue
+	var root *yaml.Node
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return fmt.Errorf("expected a mapping at the top level")
 		}
+	}
 
-		if inDependencies {
-			trimmed := strings.TrimSpace(line)
-			if strings.HasPrefix(trimmed, "-") {
-				// Extract dependency name
-				dep := strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
-				dep = strings.Trim(dep, "\"'")
-				existingDeps[dep] = true
-			} else if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
-				// Not a dependency item, end of dependencies section
+	var key, value *yaml.Node
+	if root != nil {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == "dependencies" {
+				key, value = root.Content[i], root.Content[i+1]
 				break
 			}
 		}
 	}
 
-	// Merge: add new dependencies to existing ones
+	var existing []*yaml.Node
+	if value != nil {
+		switch {
+		case value.Kind == yaml.SequenceNode:
+			existing = value.Content
+		case value.Kind != yaml.ScalarNode || value.Tag != "!!null":
+			return fmt.Errorf("dependencies must be a list")
+		}
+	}
+
+	// Merge with the existing entries and sort
 	allDeps := make(map[string]bool)
-	for dep := range existingDeps {
-		allDeps[dep] = true
+	var existingDeps []string
+	for _, item := range existing {
+		if item.Kind != yaml.ScalarNode {
+			return fmt.Errorf("dependencies must be a list of strings")
+		}
+		allDeps[item.Value] = true
+		existingDeps = append(existingDeps, item.Value)
 	}
 	for _, dep := range dependencies {
 		allDeps[dep] = true
 	}
 
-	// Convert to sorted slice
 	var mergedDeps []string
 	for dep := range allDeps {
 		mergedDeps = append(mergedDeps, dep)
 	}
 	sort.Strings(mergedDeps)
 
-	// Second pass: rebuild file with merged dependencies
-	inDependencies = false
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
+	if slices.Equal(existingDeps, mergedDeps) {
+		return nil
+	}
 
-		// Check if this is the dependencies line
-		if strings.HasPrefix(strings.TrimSpace(line), "dependencies:") {
-			inDependencies = true
-			// Get the indent level for this section
-			indentLevel = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
-
-			// Add the dependencies line
-			result = append(result, line)
-
-			// Skip old dependency entries
-			for i+1 < len(lines) {
-				nextLine := lines[i+1]
-				trimmed := strings.TrimSpace(nextLine)
-				// If it's a dependency item or empty, skip it
-				if strings.HasPrefix(trimmed, "-") || (trimmed == "" && i+2 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+2]), "-")) {
-					i++
-					continue
-				}
-				break
-			}
+	lines := strings.SplitAfter(string(data), "\n")
 
-			// Add merged dependencies
-			for _, dep := range mergedDeps {
-				result = append(result, fmt.Sprintf("%s  - \"%s\"", indentLevel, dep))
+	// A block list keeps the source lines of its entries, together with the
+	// comments above them; other entries are written as quoted strings
+	if len(existing) > 0 && value.Style&yaml.FlowStyle == 0 {
+		indent := strings.Repeat(" ", value.Column-1)
+		first, last := existing[0].Line-1, existing[len(existing)-1].Line-1
+
+		entryLines := make(map[string][]string)
+		start := first
+		for _, item := range existing {
+			if _, exists := entryLines[item.Value]; !exists {
+				entryLines[item.Value] = lines[start:item.Line]
 			}
+			start = item.Line
+		}
 
-			inDependencies = false
-			continue
+		var list []string
+		for _, dep := range mergedDeps {
+			if source, exists := entryLines[dep]; exists {
+				list = append(list, source...)
+			} else {
+				list = append(list, fmt.Sprintf("%s- \"%s\"\n", indent, dep))
+			}
 		}
 
-		if !inDependencies {
-			result = append(result, line)
+		// Keep a missing trailing newline missing
+		if last == len(lines)-1 && !strings.HasSuffix(lines[last], "\n") {
+			list[len(list)-1] = strings.TrimSuffix(list[len(list)-1], "\n")
 		}
+
+		result := append(append(append([]string(nil), lines[:first]...), list...), lines[last+1:]...)
+		return os.WriteFile(path, []byte(strings.Join(result, "")), 0644)
 	}
 
-	// If dependencies weren't found, add them at the end
-	if !dependenciesFound && len(mergedDeps) > 0 {
-		result = append(result, "dependencies:")
+	// Otherwise write a block list in place of an empty or flow list, or
+	// append one when the file has no dependencies
+	var list []string
+	if key == nil {
+		if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+			lines[len(lines)-1] += "\n"
+		}
+		list = append(list, "dependencies:\n")
 		for _, dep := range mergedDeps {
-			result = append(result, fmt.Sprintf("  - \"%s\"", dep))
+			list = append(list, fmt.Sprintf("  - \"%s\"\n", dep))
 		}
+		return os.WriteFile(path, []byte(strings.Join(append(lines, list...), "")), 0644)
+	}
+
+	keyLine := key.Line - 1
+	lastLine := keyLine
+	if value.Kind == yaml.SequenceNode {
+		lastLine = flowSequenceEnd(lines, value)
 	}
+	indent := strings.Repeat(" ", key.Column-1)
+	list = append(list, indent+"dependencies:\n")
+	for _, dep := range mergedDeps {
+		list = append(list, fmt.Sprintf("%s  - \"%s\"\n", indent, dep))
+	}
+
+	result := append(append(append([]string(nil), lines[:keyLine]...), list...), lines[lastLine+1:]...)
+	return os.WriteFile(path, []byte(strings.Join(result, "")), 0644)
+}
 
-	return os.WriteFile(path, []byte(strings.Join(result, "\n")), 0644)
+// flowSequenceEnd returns the index of the line holding the ] that closes the
+// flow sequence value, which may span several lines. Brackets in quoted
+// strings and comments are skipped.
+func flowSequenceEnd(lines []string, value *yaml.Node) int {
+	depth := 0
+	var quote byte
+	for i := value.Line - 1; i < len(lines); i++ {
+		line := lines[i]
+		start := 0
+		if i == value.Line-1 {
+			start = value.Column - 1
+		}
+		for j := start; j < len(line); j++ {
+			c := line[j]
+			switch {
+			case quote != 0:
+				if c == '\\' && quote == '"' {
+					j++
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '#' && (j == 0 || line[j-1] == ' ' || line[j-1] == '\t'):
+				j = len(line)
+			case c == '[':
+				depth++
+			case c == ']':
+				depth--
+				if depth == 0 {
+					return i
+				}
+			}
+		}
+	}
+	return len(lines) - 1
 }
 
-// updateProjectJsonDependencies updates the implicitDependencies in a project.json file
-// Uses JSON parsing to ensure proper formatting
+// updateProjectJsonDependencies merges dependencies into the implicitDependencies
+// of a project.json file. Other fields are kept as raw JSON in their original
+// order; a file that is not a valid JSON object is reported and left unchanged.
 func updateProjectJsonDependencies(path string, dependencies []string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
 
-	// First, extract existing dependencies (even from malformed JSON)
-	existingDeps := make(map[string]bool)
-	lines := strings.Split(string(data), "\n")
-	inDeps := false
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if strings.Contains(trimmed, "\"implicitDependencies\"") {
-			inDeps = true
-			continue
-		}
-		if inDeps {
-			if strings.Contains(trimmed, "]") {
-				break
-			}
-			if strings.Contains(trimmed, "\"") {
-				start := strings.Index(trimmed, "\"")
-				end := strings.LastIndex(trimmed, "\"")
-				if start != -1 && end != -1 && start < end {
-					dep := trimmed[start+1 : end]
-					if dep != "" && dep != "implicitDependencies" {
-						existingDeps[dep] = true
-					}
-				}
-			}
+	keys, fields, err := decodeJSONObject(data)
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var existingDeps []string
+	if raw, exists := fields["implicitDependencies"]; exists {
+		if err := json.Unmarshal(raw, &existingDeps); err != nil {
+			return fmt.Errorf("implicitDependencies must be a list of strings: %w", err)
 		}
+	} else {
+		keys = append(keys, "implicitDependencies")
 	}
 
-	// Merge with new dependencies
+	// Merge with the existing entries and sort
 	allDeps := make(map[string]bool)
-	for dep := range existingDeps {
+	for _, dep := range existingDeps {
 		allDeps[dep] = true
 	}
 	for _, dep := range dependencies {
 		allDeps[dep] = true
 	}
 
-	// Convert to sorted slice
-	var mergedDeps []string
+	mergedDeps := make([]string, 0, len(allDeps))
 	for dep := range allDeps {
 		mergedDeps = append(mergedDeps, dep)
 	}
 	sort.Strings(mergedDeps)
 
-	// Try to parse JSON (might fail if malformed)
-	var jsonData map[string]interface{}
-	if err := json.Unmarshal(data, &jsonData); err != nil {
-		// Try to fix common JSON errors
-		fixed := string(data)
-		// Fix: missing comma after } when followed by "
-		fixed = strings.ReplaceAll(fixed, "}\n  \"", "},\n  \"")
-		fixed = strings.ReplaceAll(fixed, "}\n \"", "},\n \"")
-		// Fix: trailing comma before closing }
-		fixed = strings.ReplaceAll(fixed, "],\n}", "]\n}")
+	if slices.Equal(existingDeps, mergedDeps) {
+		return nil
+	}
+
+	fields["implicitDependencies"], err = json.Marshal(mergedDeps)
+	if err != nil {
+		return fmt.Errorf("marshal failed: %w", err)
+	}
 
-		if err := json.Unmarshal([]byte(fixed), &jsonData); err != nil {
-			return fmt.Errorf("invalid JSON: %w", err)
+	var output bytes.Buffer
+	output.WriteString("{\n")
+	for i, key := range keys {
+		name, _ := json.Marshal(key)
+		output.WriteString("  ")
+		output.Write(name)
+		output.WriteString(": ")
+		if err := json.Indent(&output, fields[key], "  ", "  "); err != nil {
+			return fmt.Errorf("marshal failed: %w", err)
+		}
+		if i < len(keys)-1 {
+			output.WriteString(",")
 		}
+		output.WriteString("\n")
 	}
+	output.WriteString("}\n")
+
+	return os.WriteFile(path, output.Bytes(), 0644)
+}
 
-	// Update dependencies
-	jsonData["implicitDependencies"] = mergedDeps
+// decodeJSONObject decodes a JSON object into its keys, in document order, and
+// their raw values
+func decodeJSONObject(data []byte) ([]string, map[string]json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
 
-	// Marshal with proper indentation
-	output, err := json.MarshalIndent(jsonData, "", "  ")
+	token, err := decoder.Token()
 	if err != nil {
-		return fmt.Errorf("marshal failed: %w", err)
+		return nil, nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, nil, fmt.Errorf("expected an object")
+	}
+
+	var keys []string
+	fields := make(map[string]json.RawMessage)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := token.(string)
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		if _, exists := fields[key]; exists {
+			return nil, nil, fmt.Errorf("duplicate key %q", key)
+		}
+		keys = append(keys, key)
+		fields[key] = value
+	}
+
+	// The closing brace, then nothing but whitespace
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, nil, fmt.Errorf("unexpected data after the object")
 	}
 
-	// Write with trailing newline
-	return os.WriteFile(path, append(output, '\n'), 0644)
+	return keys, fields, nil
 }
@@ -0,0 +1,343 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"duck/internal/config"
+	"duck/internal/state"
+
+	"github.com/urfave/cli/v2"
+)
+
+// runContext parses args with the flags of run
+func runContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+	set := flag.NewFlagSet("run", flag.ContinueOnError)
+	for _, f := range runFlags() {
+		if err := f.Apply(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+func TestSelectProjectsFromFileWithSelectors(t *testing.T) {
+	projects := map[string]*config.AppProject{
+		"apps/api": {Config: &config.AppConfig{Name: "api", Namespace: "core", Tags: []string{"go"}}},
+		"apps/web": {Config: &config.AppConfig{Name: "web", Namespace: "core", Tags: []string{"go"}, Dependencies: []string{"apps/api"}}},
+		"apps/ui":  {Config: &config.AppConfig{Name: "ui", Namespace: "core", Tags: []string{"node"}}},
+	}
+
+	selection := filepath.Join(t.TempDir(), "selection.txt")
+	if err := os.WriteFile(selection, []byte("apps/web\napps/ui\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"file only", nil, []string{"apps/web", "apps/ui"}},
+		{"filter", []string{"--filter", `tag("go")`}, []string{"apps/web"}},
+		{"with deps", []string{"--filter", `tag("go")`, "--with-deps"}, []string{"apps/api", "apps/web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := runContext(t, append([]string{"--projects-from-file", selection}, tt.args...)...)
+			got, err := selectTargetProjects(c, projects)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectTargetProjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectProjectsFromJSONWithSelectors(t *testing.T) {
+	projects := map[string]*config.AppProject{
+		"apps/api": {Config: &config.AppConfig{Name: "api", Namespace: "core", Tags: []string{"go"}}},
+		"apps/web": {Config: &config.AppConfig{Name: "web", Namespace: "core", Tags: []string{"go"}, Dependencies: []string{"apps/api"}}},
+		"apps/ui":  {Config: &config.AppConfig{Name: "ui", Namespace: "core", Tags: []string{"node"}}},
+	}
+	selection := `[{"key":"apps/web"},{"key":"apps/ui"}]`
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"json only", nil, []string{"apps/ui", "apps/web"}},
+		{"filter", []string{"--filter", `tag("go")`}, []string{"apps/web"}},
+		{"with deps", []string{"--filter", `tag("go")`, "--with-deps"}, []string{"apps/api", "apps/web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := runContext(t, append([]string{"--projects-json", selection}, tt.args...)...)
+			got, err := selectTargetProjects(c, projects)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectTargetProjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFailedLastRunWithSelectors(t *testing.T) {
+	projects := map[string]*config.AppProject{
+		"apps/api": {Config: &config.AppConfig{Name: "api", Namespace: "core", Tags: []string{"go"}}},
+		"apps/web": {Config: &config.AppConfig{Name: "web", Namespace: "core", Tags: []string{"go"}, Dependencies: []string{"apps/api"}}},
+		"apps/ui":  {Config: &config.AppConfig{Name: "ui", Namespace: "core", Tags: []string{"node"}}},
+	}
+
+	// The run state is read from the current directory
+	dir := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	runState, err := state.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runState.RecordResults("test", []string{"apps/api"}, []string{"apps/web", "apps/ui"})
+	if err := runState.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"failures only", nil, []string{"apps/ui", "apps/web"}},
+		{"filter", []string{"--filter", `tag("go")`}, []string{"apps/web"}},
+		{"with deps", []string{"--filter", `tag("go")`, "--with-deps"}, []string{"apps/api", "apps/web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := runContext(t, append([]string{"--script", "test", "--only-failed-last-run"}, tt.args...)...)
+			got, err := selectTargetProjects(c, projects)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectTargetProjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoInteractive(t *testing.T) {
+	script := config.Script{Command: "npm login"}
+	service := config.Script{Command: "go run .", ReadyWhen: "^listening"}
+
+	tests := []struct {
+		name     string
+		args     []string
+		projects int
+		script   config.Script
+		terminal bool
+		want     bool
+	}{
+		{"single project on a terminal", nil, 1, script, true, true},
+		{"not a terminal", nil, 1, script, false, false},
+		{"several projects", nil, 2, script, true, false},
+		{"turned off", []string{"--interactive=false"}, 1, script, true, false},
+		{"events", []string{"--events"}, 1, script, true, false},
+		{"combine output", []string{"--combine-output"}, 1, script, true, false},
+		{"output lines", []string{"--output-lines", "20"}, 1, script, true, false},
+		{"service", nil, 1, service, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := runContext(t, tt.args...)
+			if got := autoInteractive(c, tt.projects, tt.script, tt.terminal); got != tt.want {
+				t.Errorf("autoInteractive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckServiceWritable(t *testing.T) {
+	readOnly := globalOptions.ReadOnly
+	t.Cleanup(func() { globalOptions.ReadOnly = readOnly })
+
+	service := config.Script{Command: "go run .", ReadyWhen: "^listening"}
+	script := config.Script{Command: "go test ./..."}
+
+	globalOptions.ReadOnly = false
+	if err := checkServiceWritable(service); err != nil {
+		t.Errorf("service without --read-only: %v", err)
+	}
+
+	globalOptions.ReadOnly = true
+	if err := checkServiceWritable(script); err != nil {
+		t.Errorf("plain script with --read-only: %v", err)
+	}
+	if err := checkServiceWritable(service); err == nil {
+		t.Error("service with --read-only: want an error for its log")
+	}
+}
+
+func TestExecCommandLine(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"plain words", []string{"go", "mod", "tidy"}, "go mod tidy"},
+		{"variables", []string{"echo", "{projectName}"}, "echo {projectName}"},
+		{"spaces", []string{"git", "commit", "-m", "fix the build"}, "git commit -m 'fix the build'"},
+		{"single quote", []string{"echo", "it's"}, `echo 'it'\''s'`},
+		{"shell operators", []string{"echo", "a", "&&", "rm", "*"}, "echo a '&&' rm '*'"},
+		{"empty", []string{"printf", ""}, "printf ''"},
+		{"single command line", []string{`go vet ./... && echo "ok"`}, `go vet ./... && echo "ok"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := execCommandLine(tt.args); got != tt.want {
+				t.Errorf("execCommandLine(%q) = %s, want %s", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateAppYamlDependencies(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "multi-line flow list",
+			in:   "name: api\ndependencies: [\n  \"shared/db\",\n  \"shared/log\"\n]\ntags: [go]\n",
+			want: "name: api\ndependencies:\n  - \"shared/auth\"\n  - \"shared/db\"\n  - \"shared/log\"\ntags: [go]\n",
+		},
+		{
+			name: "closing bracket after a comment",
+			in:   "name: api\ndependencies: [\"shared/db\", # the ] here is a comment\n  \"shared/log\"]\ntags: [go]\n",
+			want: "name: api\ndependencies:\n  - \"shared/auth\"\n  - \"shared/db\"\n  - \"shared/log\"\ntags: [go]\n",
+		},
+		{
+			name: "empty multi-line flow list",
+			in:   "name: api\ndependencies: [\n]\ntags: [go]\n",
+			want: "name: api\ndependencies:\n  - \"shared/auth\"\ntags: [go]\n",
+		},
+		{
+			name: "single-line flow list",
+			in:   "name: api\ndependencies: [\"shared/db\"]\n",
+			want: "name: api\ndependencies:\n  - \"shared/auth\"\n  - \"shared/db\"\n",
+		},
+		{
+			name: "block list keeps comments",
+			in:   "name: api\ndependencies:\n  - shared/log\n  # storage\n  - shared/db\n",
+			want: "name: api\ndependencies:\n  - \"shared/auth\"\n  # storage\n  - shared/db\n  - shared/log\n",
+		},
+		{
+			name: "no dependencies",
+			in:   "name: api",
+			want: "name: api\ndependencies:\n  - \"shared/auth\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.yaml")
+			if err := os.WriteFile(path, []byte(tt.in), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := updateAppYamlDependencies(path, []string{"shared/auth"}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("app.yaml =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandEnvironment(t *testing.T) {
+	env := []string{"HOME=/home/duck", "GOFLAGS=-mod=mod", "EMPTY="}
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"go build $GOFLAGS .", "go build -mod=mod ."},
+		{"cp bin ${HOME}/bin", "cp bin /home/duck/bin"},
+		{`echo "$HOME's files"`, `echo "/home/duck's files"`},
+		{"echo '$HOME'", "echo '$HOME'"},
+		{`echo \$HOME`, `echo \$HOME`},
+		{"echo [$EMPTY]", "echo []"},
+		{"FOO=1; echo $FOO $1 $$", "FOO=1; echo $FOO $1 $$"},
+		{"echo ${HOME", "echo ${HOME"},
+	}
+
+	for _, tt := range tests {
+		if got := expandEnvironment(tt.command, env); got != tt.want {
+			t.Errorf("expandEnvironment(%s) = %s, want %s", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestListMarkersFollowColorSettings(t *testing.T) {
+	t.Cleanup(func() { colorEnabled = false })
+	t.Setenv("NO_COLOR", "")
+
+	tests := []struct {
+		mode    string
+		noColor bool
+		colored bool
+	}{
+		{"always", false, true},
+		{"never", false, false},
+		{"auto", true, false},
+	}
+
+	for _, tt := range tests {
+		if err := setupColor(tt.mode, tt.noColor); err != nil {
+			t.Fatal(err)
+		}
+		for _, got := range []string{colorHeading("📁 core"), colorProject("🦆 api")} {
+			if colored := strings.HasPrefix(got, "\033["); colored != tt.colored {
+				t.Errorf("--color %s, --no-color=%t: marker %q colored = %t, want %t", tt.mode, tt.noColor, got, colored, tt.colored)
+			}
+		}
+	}
+
+	// NO_COLOR turns colors off even where --no-color is not passed
+	t.Setenv("NO_COLOR", "1")
+	if err := setupColor("auto", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := colorHeading("📁 core"); got != "📁 core" {
+		t.Errorf("with NO_COLOR set, heading = %q, want it uncolored", got)
+	}
+}
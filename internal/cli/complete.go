@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"duck/internal/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+// AllProjectKeys returns the sorted keys of the projects of the workspace, for
+// shell completion and editor integrations. Outside a command it looks for the
+// workspace from the current directory, as duck does on startup.
+func AllProjectKeys() ([]string, error) {
+	if err := ensureWorkspace(); err != nil {
+		return nil, err
+	}
+
+	_, scanner, err := loadProjectScanner()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(scanner.GetProjects()))
+	for key := range scanner.GetProjects() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// AllScriptNames returns the sorted names of the scripts of duck.yaml, for
+// shell completion and editor integrations
+func AllScriptNames() ([]string, error) {
+	if err := ensureWorkspace(); err != nil {
+		return nil, err
+	}
+
+	projectConfig, err := config.LoadProjectConfigWithOptions(globalOptions.ConfigFile, config.LoadOptions{
+		NoDefaultIgnores: globalOptions.NoDefaultIgnores,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project config: %w", err)
+	}
+	return sortedScriptNames(projectConfig), nil
+}
+
+// ensureWorkspace enters the workspace root from the current directory unless
+// the global flags have already been applied
+func ensureWorkspace() error {
+	if globalOptions.ConfigFile != "" {
+		return nil
+	}
+	globalOptions.ConfigFile = config.WorkspaceConfigFile
+	return enterWorkspaceRoot("")
+}
+
+// completeSelection completes the values of --project and --script with
+// project keys and script names, and otherwise the command's flags
+func completeSelection(c *cli.Context) {
+	if err := applyGlobalFlags(c, true); err != nil {
+		return
+	}
+
+	// The shell passes the words typed so far; the last is the completion flag
+	var previous string
+	if len(os.Args) > 2 {
+		previous = os.Args[len(os.Args)-2]
+	}
+
+	switch previous {
+	case "--project", "-p":
+		printCompletions(c, AllProjectKeys)
+	case "--script", "-s":
+		printCompletions(c, AllScriptNames)
+	default:
+		cli.DefaultCompleteWithFlags(c.Command)(c)
+	}
+}
+
+// completeProjectArgument completes a project key argument, such as the
+// project of `duck info`
+func completeProjectArgument(c *cli.Context) {
+	if err := applyGlobalFlags(c, true); err != nil {
+		return
+	}
+
+	if len(os.Args) > 2 && strings.HasPrefix(os.Args[len(os.Args)-2], "-") {
+		cli.DefaultCompleteWithFlags(c.Command)(c)
+		return
+	}
+	if c.Args().Len() == 0 {
+		printCompletions(c, AllProjectKeys)
+	}
+}
+
+// printCompletions prints the values returned by list, one per line. Errors
+// are dropped: completion offers nothing rather than breaking the shell.
+func printCompletions(c *cli.Context, list func() ([]string, error)) {
+	values, err := list()
+	if err != nil {
+		return
+	}
+	for _, value := range values {
+		fmt.Fprintln(c.App.Writer, value)
+	}
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"duck/internal/config"
+	"duck/internal/dependencyscanner"
+
+	"golang.org/x/mod/semver"
+)
+
+// DependencyInfo is the machine-readable form of a single dependency
+type DependencyInfo struct {
+	Module      string   `json:"module"`
+	Version     string   `json:"version,omitempty"`
+	Direct      bool     `json:"direct"`
+	Internal    bool     `json:"internal"`
+	Project     string   `json:"project,omitempty"`
+	IndirectVia []string `json:"indirectVia,omitempty"`
+}
+
+// ProjectDependencyInfo is the machine-readable form of a project's dependencies
+type ProjectDependencyInfo struct {
+	Project      string           `json:"project"`
+	Language     string           `json:"language"`
+	Dependencies []DependencyInfo `json:"dependencies"`
+}
+
+// VersionConflictInfo is the machine-readable form of a dependency required at
+// several versions
+type VersionConflictInfo struct {
+	Module   string                `json:"module"`
+	Versions []VersionRequirements `json:"versions"`
+}
+
+// VersionRequirements lists the projects requiring one version of a dependency
+type VersionRequirements struct {
+	Version  string   `json:"version"`
+	Projects []string `json:"projects"`
+}
+
+// printDependenciesJSON writes the dependency report for projects as JSON
+func printDependenciesJSON(projects []*dependencyscanner.ProjectDependencies, localPackages map[string]bool, allProjects map[string]*config.AppProject, workModules map[string]string, paths *pathFormatter) error {
+	infos := make([]ProjectDependencyInfo, 0, len(projects))
+
+	for _, project := range projects {
+		info := ProjectDependencyInfo{
+			Project:      paths.FormatKey(project.ProjectPath),
+			Language:     project.Language,
+			Dependencies: make([]DependencyInfo, 0, len(project.Dependencies)),
+		}
+
+		for _, dep := range project.Dependencies {
+			depInfo := DependencyInfo{
+				Module:      dep.Target,
+				Version:     dep.Version,
+				Direct:      dep.IsDirect,
+				Internal:    localPackages[dep.Target],
+				IndirectVia: dep.Via,
+			}
+			if depInfo.Internal {
+				if projectKey := dependencyProjectKey(dep, allProjects, workModules); projectKey != "" {
+					depInfo.Project = paths.FormatKey(projectKey)
+				}
+			}
+			info.Dependencies = append(info.Dependencies, depInfo)
+		}
+
+		infos = append(infos, info)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(infos)
+}
+
+// printDependencyEdges prints one line per internal dependency of each project:
+// "source<TAB>target<TAB>direct|indirect". Targets are mapped to project keys
+// like in the main report, falling back to the module path.
+func printDependencyEdges(projects []*dependencyscanner.ProjectDependencies, localPackages map[string]bool, allProjects map[string]*config.AppProject, workModules map[string]string, paths *pathFormatter) {
+	for _, project := range projects {
+		source := paths.FormatKey(project.ProjectPath)
+
+		for _, dep := range project.Dependencies {
+			if !localPackages[dep.Target] {
+				continue
+			}
+
+			target := dep.Target
+			if projectKey := dependencyProjectKey(dep, allProjects, workModules); projectKey != "" {
+				target = paths.FormatKey(projectKey)
+			}
+
+			kind := "direct"
+			if !dep.IsDirect {
+				kind = "indirect"
+			}
+
+			fmt.Printf("%s\t%s\t%s\n", source, target, kind)
+		}
+	}
+}
+
+// printVersionConflicts reports external dependencies required at more than one
+// version, as text or JSON. Versions are listed from oldest to newest.
+func printVersionConflicts(conflicts []dependencyscanner.VersionConflict, localPackages map[string]bool, jsonOutput bool, paths *pathFormatter) error {
+	infos := make([]VersionConflictInfo, 0, len(conflicts))
+
+	for _, conflict := range conflicts {
+		if localPackages[conflict.Target] {
+			continue
+		}
+
+		versions := make([]string, 0, len(conflict.Versions))
+		for version := range conflict.Versions {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool {
+			if c := semver.Compare(versions[i], versions[j]); c != 0 {
+				return c < 0
+			}
+			return versions[i] < versions[j]
+		})
+
+		info := VersionConflictInfo{Module: conflict.Target}
+		for _, version := range versions {
+			requirements := VersionRequirements{Version: version}
+			for _, projectPath := range conflict.Versions[version] {
+				requirements.Projects = append(requirements.Projects, paths.FormatKey(projectPath))
+			}
+			info.Versions = append(info.Versions, requirements)
+		}
+		infos = append(infos, info)
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(infos)
+	}
+
+	if len(infos) == 0 {
+		fmt.Println("No version conflicts found.")
+		return nil
+	}
+
+	fmt.Printf("Found %d version conflict(s):\n\n", len(infos))
+	for _, info := range infos {
+		fmt.Printf("%s\n", info.Module)
+		for _, requirements := range info.Versions {
+			fmt.Printf("   %s: %s\n", requirements.Version, strings.Join(requirements.Projects, ", "))
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"duck/internal/executor"
+)
+
+// RunEvent is one line of the newline-delimited JSON written by `duck run --events`
+type RunEvent struct {
+	Event   string `json:"event"`
+	Project string `json:"project,omitempty"`
+	Script  string `json:"script,omitempty"`
+
+	// output
+	Stream string  `json:"stream,omitempty"`
+	Line   *string `json:"line,omitempty"`
+
+	// finish
+	Success    *bool  `json:"success,omitempty"`
+	ExitCode   *int   `json:"exitCode,omitempty"`
+	Attempts   int    `json:"attempts,omitempty"`
+	DurationMs *int64 `json:"durationMs,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	// skip
+	Dependency string `json:"dependency,omitempty"`
+
+	// summary
+	Total       *int `json:"total,omitempty"`
+	Passed      *int `json:"passed,omitempty"`
+	Failed      *int `json:"failed,omitempty"`
+	Skipped     *int `json:"skipped,omitempty"`
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+// eventStream writes RunEvents to a writer, one JSON object per line. It is
+// safe for concurrent use, as the executor reports stdout and stderr lines
+// from separate goroutines.
+type eventStream struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newEventStream(w io.Writer) *eventStream {
+	return &eventStream{encoder: json.NewEncoder(w)}
+}
+
+func (s *eventStream) write(event RunEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.encoder.Encode(event)
+}
+
+// executorEvent converts the progress reported by the executor
+func (s *eventStream) executorEvent(event executor.Event) {
+	switch event.Type {
+	case executor.EventStart:
+		s.write(RunEvent{Event: "start", Project: event.ProjectKey, Script: event.Script})
+	case executor.EventOutput:
+		line := event.Line
+		s.write(RunEvent{Event: "output", Project: event.ProjectKey, Stream: event.Stream, Line: &line})
+	case executor.EventFinish:
+		result := event.Result
+		durationMs := result.Duration.Milliseconds()
+		finish := RunEvent{
+			Event:      "finish",
+			Project:    event.ProjectKey,
+			Script:     event.Script,
+			Success:    &result.Success,
+			ExitCode:   &result.ExitCode,
+			Attempts:   result.Attempts,
+			DurationMs: &durationMs,
+		}
+		if !result.Success {
+			finish.Error = strings.TrimSpace(result.Error)
+		}
+		s.write(finish)
+	}
+}
+
+// skip reports a project not run because dependency did not succeed
+func (s *eventStream) skip(projectKey, dependency string) {
+	s.write(RunEvent{Event: "skip", Project: projectKey, Dependency: dependency})
+}
+
+// summary reports the outcome of the whole run
+func (s *eventStream) summary(script string, total, passed, failed, skipped int, interrupted bool, duration time.Duration) {
+	success := failed == 0 && !interrupted
+	durationMs := duration.Milliseconds()
+	s.write(RunEvent{
+		Event:       "summary",
+		Script:      script,
+		Success:     &success,
+		DurationMs:  &durationMs,
+		Total:       &total,
+		Passed:      &passed,
+		Failed:      &failed,
+		Skipped:     &skipped,
+		Interrupted: interrupted,
+	})
+}
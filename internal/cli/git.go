@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitChangedFiles returns the absolute paths of the files that differ between
+// commit and the working tree of the git repository containing dir, including
+// untracked files
+func gitChangedFiles(dir, commit string) ([]string, error) {
+	root, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+
+	changed, err := gitOutput(dir, "diff", "--name-only", commit, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitOutput(dir, "ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(changed+"\n"+untracked, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, filepath.Join(root, filepath.FromSlash(line)))
+		}
+	}
+	return files, nil
+}
+
+// gitDirtyFiles returns the absolute paths of the files with staged, unstaged
+// or untracked changes in the working tree of the git repository containing dir
+func gitDirtyFiles(dir string) ([]string, error) {
+	root, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+
+	status, err := gitOutput(dir, "status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries look like "XY path"; renames and copies are followed by an
+	// entry holding the original path, which changed as well
+	var files []string
+	entries := strings.Split(status, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		files = append(files, filepath.Join(root, filepath.FromSlash(entry[3:])))
+
+		if (entry[0] == 'R' || entry[0] == 'C') && i+1 < len(entries) && entries[i+1] != "" {
+			i++
+			files = append(files, filepath.Join(root, filepath.FromSlash(entries[i])))
+		}
+	}
+	return files, nil
+}
+
+// gitFilesChangedSince returns the absolute paths of the files touched by the
+// commits made since a point in time, in the git repository containing dir.
+// since is anything `git log --since` accepts, such as "2026-10-01" or
+// "2 days ago"; a Go duration such as "48h" is converted to the time it
+// reaches back to.
+func gitFilesChangedSince(dir, since string) ([]string, error) {
+	if duration, err := time.ParseDuration(since); err == nil {
+		if duration < 0 {
+			return nil, fmt.Errorf("--since must not be a negative duration")
+		}
+		since = time.Now().Add(-duration).Format(time.RFC3339)
+	}
+
+	root, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+
+	changed, err := gitOutput(dir, "log", "--since="+since, "--name-only", "--pretty=format:", "--")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, line := range strings.Split(changed, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !seen[line] {
+			seen[line] = true
+			files = append(files, filepath.Join(root, filepath.FromSlash(line)))
+		}
+	}
+	return files, nil
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(output), nil
+}
+
+// changedProjectDirs returns the project directories (relative to
+// absWorkspaceRoot, as listed in projectDirs) that contain one of files
+func changedProjectDirs(absWorkspaceRoot string, projectDirs, files []string) []string {
+	canonicalFiles := make([]string, len(files))
+	for i, file := range files {
+		canonicalFiles[i] = canonicalPath(file)
+	}
+
+	var changed []string
+	for _, dir := range projectDirs {
+		prefix := canonicalPath(filepath.Join(absWorkspaceRoot, dir)) + string(filepath.Separator)
+		for _, file := range canonicalFiles {
+			if strings.HasPrefix(file, prefix) {
+				changed = append(changed, dir)
+				break
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,249 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"duck/internal/config"
+	"duck/internal/resolver"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ShowGraph exports the project dependency graph as a DOT or mermaid diagram.
+// Edges point from a project to the projects it depends on.
+func ShowGraph(c *cli.Context) error {
+	_, projects, err := LoadProjectData()
+	if err != nil {
+		return err
+	}
+
+	depth := c.Int("depth")
+	if depth < 0 {
+		return fmt.Errorf("depth must not be negative")
+	}
+
+	keys := make([]string, 0, len(projects))
+	focus := ""
+	if name := c.String("focus"); name != "" {
+		focus, err = ResolveProjectKey(name, projects)
+		if err != nil {
+			return err
+		}
+
+		r := resolver.New(projects)
+		keys = append(keys, focus)
+		keys = append(keys, r.GetDependenciesWithin(focus, depth)...)
+		keys = append(keys, r.GetDependentsWithin(focus, depth)...)
+	} else {
+		if depth > 0 {
+			return fmt.Errorf("--depth can only be used with --focus")
+		}
+		for key := range projects {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	graph := newProjectGraph(keys, projects, focus)
+
+	graph.clusterBy = c.String("cluster-by")
+	graph.tagPrefix = c.String("tag-prefix")
+	switch graph.clusterBy {
+	case clusterByNamespace, clusterByTag, clusterByNone:
+	default:
+		return fmt.Errorf("invalid cluster-by: must be 'namespace', 'tag', or 'none', got '%s'", graph.clusterBy)
+	}
+	if graph.tagPrefix != "" && graph.clusterBy != clusterByTag {
+		return fmt.Errorf("--tag-prefix can only be used with --cluster-by tag")
+	}
+
+	switch format := c.String("format"); format {
+	case "dot":
+		return graph.writeDot(os.Stdout)
+	case "mermaid":
+		return graph.writeMermaid(os.Stdout)
+	default:
+		return fmt.Errorf("invalid graph format: must be 'dot' or 'mermaid', got '%s'", format)
+	}
+}
+
+// Ways of grouping projects into clusters in exported diagrams
+const (
+	clusterByNamespace = "namespace"
+	clusterByTag       = "tag"
+	clusterByNone      = "none"
+)
+
+// projectGraph is the part of the dependency graph that is exported
+type projectGraph struct {
+	keys      []string // Sorted keys of the included projects
+	projects  map[string]*config.AppProject
+	edges     map[string][]string // Sorted dependencies of each project, limited to included projects
+	focus     string              // Highlighted project, if any
+	clusterBy string              // clusterByNamespace (the default), clusterByTag or clusterByNone
+	tagPrefix string              // With clusterByTag, only tags with this prefix form clusters
+}
+
+func newProjectGraph(keys []string, projects map[string]*config.AppProject, focus string) *projectGraph {
+	included := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		included[key] = true
+	}
+
+	edges := make(map[string][]string, len(keys))
+	for _, key := range keys {
+		for _, dep := range projects[key].Config.Dependencies {
+			if included[dep] {
+				edges[key] = append(edges[key], dep)
+			}
+		}
+		sort.Strings(edges[key])
+	}
+
+	return &projectGraph{keys: keys, projects: projects, edges: edges, focus: focus, clusterBy: clusterByNamespace}
+}
+
+// clusters groups the included project keys by namespace or tag, returning
+// the sorted cluster names, their members, and the keys in no cluster
+func (g *projectGraph) clusters() ([]string, map[string][]string, []string) {
+	members := make(map[string][]string)
+	var unclustered []string
+	for _, key := range g.keys {
+		name, ok := g.clusterOf(g.projects[key])
+		if !ok {
+			unclustered = append(unclustered, key)
+			continue
+		}
+		members[name] = append(members[name], key)
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, members, unclustered
+}
+
+// clusterOf returns the cluster project belongs to. With clusterByTag that is
+// its first tag starting with tagPrefix; projects without one are not clustered.
+func (g *projectGraph) clusterOf(project *config.AppProject) (string, bool) {
+	switch g.clusterBy {
+	case clusterByNone:
+		return "", false
+	case clusterByTag:
+		for _, tag := range project.Config.Tags {
+			if strings.HasPrefix(tag, g.tagPrefix) {
+				return tag, true
+			}
+		}
+		return "", false
+	}
+	return project.Config.Namespace, true
+}
+
+func (g *projectGraph) writeDot(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("digraph duck {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	node := func(indent, key string) {
+		attributes := fmt.Sprintf("label=%s", dotQuote(g.projects[key].Config.Name))
+		if key == g.focus {
+			attributes += ", style=bold"
+		}
+		fmt.Fprintf(&b, "%s%s [%s];\n", indent, dotQuote(key), attributes)
+	}
+
+	names, members, unclustered := g.clusters()
+	for i, name := range names {
+		fmt.Fprintf(&b, "\n  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(name))
+		for _, key := range members[name] {
+			node("    ", key)
+		}
+		b.WriteString("  }\n")
+	}
+
+	if len(unclustered) > 0 {
+		b.WriteString("\n")
+	}
+	for _, key := range unclustered {
+		node("  ", key)
+	}
+
+	if g.hasEdges() {
+		b.WriteString("\n")
+	}
+	for _, key := range g.keys {
+		for _, dep := range g.edges[key] {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(key), dotQuote(dep))
+		}
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (g *projectGraph) writeMermaid(w io.Writer) error {
+	var b strings.Builder
+
+	// Mermaid ids cannot contain '/', so nodes are numbered in key order
+	ids := make(map[string]string, len(g.keys))
+	for i, key := range g.keys {
+		ids[key] = fmt.Sprintf("n%d", i)
+	}
+
+	b.WriteString("graph LR\n")
+
+	names, members, unclustered := g.clusters()
+	for i, name := range names {
+		fmt.Fprintf(&b, "  subgraph ns%d [%s]\n", i, mermaidQuote(name))
+		for _, key := range members[name] {
+			fmt.Fprintf(&b, "    %s[%s]\n", ids[key], mermaidQuote(g.projects[key].Config.Name))
+		}
+		b.WriteString("  end\n")
+	}
+	for _, key := range unclustered {
+		fmt.Fprintf(&b, "  %s[%s]\n", ids[key], mermaidQuote(g.projects[key].Config.Name))
+	}
+
+	for _, key := range g.keys {
+		for _, dep := range g.edges[key] {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids[key], ids[dep])
+		}
+	}
+
+	if g.focus != "" {
+		fmt.Fprintf(&b, "  style %s stroke-width:3px\n", ids[g.focus])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (g *projectGraph) hasEdges() bool {
+	for _, deps := range g.edges {
+		if len(deps) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func dotQuote(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+func mermaidQuote(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"duck/internal/config"
+	"duck/internal/resolver"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectDetails is the machine-readable form of `duck info`
+type ProjectDetails struct {
+	ProjectInfo `yaml:",inline"`
+	Environment map[string]string `json:"environment" yaml:"environment"`
+	// Scripts are the scripts of duck.yaml the project does not disable
+	Scripts []string `json:"scripts" yaml:"scripts"`
+	// Dependents depend on the project directly; AllDependents also
+	// includes the projects depending on it through others
+	Dependents    []string `json:"dependents" yaml:"dependents"`
+	AllDependents []string `json:"allDependents" yaml:"allDependents"`
+}
+
+// ShowProjectInfo prints the configuration, scripts and dependents of one project
+func ShowProjectInfo(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("please specify exactly one project")
+	}
+
+	paths, err := newPathFormatter(c.String("path-style"))
+	if err != nil {
+		return err
+	}
+
+	output := c.String("output")
+	if output != "text" && output != "json" && output != "yaml" {
+		return fmt.Errorf("invalid output format: must be 'text', 'json', or 'yaml', got '%s'", output)
+	}
+
+	_, scanner, err := loadProjectScanner()
+	if err != nil {
+		return err
+	}
+	projects := scanner.GetProjects()
+
+	projectKey, err := ResolveProjectKey(c.Args().First(), projects)
+	if err != nil {
+		return err
+	}
+	project := projects[projectKey]
+
+	r := resolver.New(projects)
+	details := ProjectDetails{
+		ProjectInfo:   NewProjectInfos(map[string]*config.AppProject{projectKey: project}, paths)[0],
+		Environment:   project.Config.Environment,
+		Scripts:       scanner.GetAvailableScripts(project),
+		Dependents:    r.GetDependents(projectKey),
+		AllDependents: r.GetTransitiveDependents(projectKey),
+	}
+	sort.Strings(details.Scripts)
+	if details.Environment == nil {
+		details.Environment = map[string]string{}
+	}
+	for _, list := range []*[]string{&details.Scripts, &details.Dependents, &details.AllDependents} {
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(details)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(details)
+	}
+
+	fmt.Printf("🦆 %s (%s)\n", details.Name, details.Key)
+	fmt.Printf("  Namespace: %s\n", details.Namespace)
+	if len(details.Aliases) > 0 {
+		fmt.Printf("  Aliases: %s\n", strings.Join(details.Aliases, ", "))
+	}
+	if details.Description != "" {
+		fmt.Printf("  Description: %s\n", details.Description)
+	}
+	fmt.Printf("  Path: %s\n", details.Path)
+	printInfoList("Tags", details.Tags)
+	printInfoList("Dependencies", details.Dependencies)
+	printInfoList("Scripts", details.Scripts)
+	printInfoList("Dependents", details.Dependents)
+	printInfoList("All dependents", details.AllDependents)
+
+	if len(details.Environment) > 0 {
+		names := make([]string, 0, len(details.Environment))
+		for name := range details.Environment {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Println("  Environment:")
+		for _, name := range names {
+			fmt.Printf("    %s=%s\n", name, details.Environment[name])
+		}
+	}
+
+	return nil
+}
+
+// printInfoList prints a labelled list on one line, or "(none)" when it is empty
+func printInfoList(label string, values []string) {
+	if len(values) == 0 {
+		fmt.Printf("  %s: (none)\n", label)
+		return
+	}
+	fmt.Printf("  %s: %s\n", label, strings.Join(values, ", "))
+}
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"duck/internal/dependencyscanner"
+
+	"golang.org/x/mod/module"
+)
+
+// licenseFileNames are the files searched for a module's license, in order
+var licenseFileNames = []string{
+	"LICENSE", "LICENSE.md", "LICENSE.txt",
+	"LICENCE", "LICENCE.md", "LICENCE.txt",
+	"COPYING", "COPYING.md", "COPYING.txt",
+}
+
+// licensePatterns identifies a license by phrases of its text. Earlier entries
+// win, so more specific licenses come before the ones they resemble.
+var licensePatterns = []struct {
+	license string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "may be used to endorse or promote products"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+}
+
+// LicenseGroupInfo is the machine-readable form of the external dependencies
+// sharing a license. License is empty for dependencies without a detectable one.
+type LicenseGroupInfo struct {
+	License string               `json:"license"`
+	Modules []LicensedModuleInfo `json:"modules"`
+}
+
+// LicensedModuleInfo is an external dependency and the projects requiring it
+type LicensedModuleInfo struct {
+	Module   string   `json:"module"`
+	Version  string   `json:"version,omitempty"`
+	Projects []string `json:"projects"`
+	// Reason explains why no license was detected
+	Reason string `json:"reason,omitempty"`
+}
+
+// printLicenses groups the external dependencies of the Go projects by the
+// license found in the module cache, as text or JSON
+func printLicenses(projects []*dependencyscanner.ProjectDependencies, localPackages map[string]bool, jsonOutput bool, paths *pathFormatter) error {
+	modCache, err := goModCache()
+	if err != nil {
+		return err
+	}
+
+	modules := make(map[string]*LicensedModuleInfo)
+	licenses := make(map[string]string)
+	for _, project := range projects {
+		if project.Language != "go" {
+			continue
+		}
+
+		for _, dep := range project.Dependencies {
+			if localPackages[dep.Target] {
+				continue
+			}
+
+			id := dep.Target + "@" + dep.Version
+			info, exists := modules[id]
+			if !exists {
+				info = &LicensedModuleInfo{Module: dep.Target, Version: dep.Version}
+				modules[id] = info
+
+				dir, dirErr := dep.LocalPath, error(nil)
+				if dir == "" {
+					dir, dirErr = moduleCacheDir(modCache, dep.Target, dep.Version)
+				}
+				if dirErr != nil {
+					info.Reason = dirErr.Error()
+				} else {
+					licenses[id], info.Reason = detectLicense(dir)
+				}
+			}
+
+			projectKey := paths.FormatKey(project.ProjectPath)
+			if len(info.Projects) == 0 || info.Projects[len(info.Projects)-1] != projectKey {
+				info.Projects = append(info.Projects, projectKey)
+			}
+		}
+	}
+
+	byLicense := make(map[string][]LicensedModuleInfo)
+	for id, info := range modules {
+		byLicense[licenses[id]] = append(byLicense[licenses[id]], *info)
+	}
+
+	// Known licenses sorted by name, then the dependencies without one
+	names := make([]string, 0, len(byLicense))
+	for name := range byLicense {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, exists := byLicense[""]; exists {
+		names = append(names, "")
+	}
+
+	groups := make([]LicenseGroupInfo, 0, len(names))
+	for _, name := range names {
+		group := byLicense[name]
+		sort.Slice(group, func(i, j int) bool {
+			if group[i].Module != group[j].Module {
+				return group[i].Module < group[j].Module
+			}
+			return group[i].Version < group[j].Version
+		})
+		groups = append(groups, LicenseGroupInfo{License: name, Modules: group})
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(groups)
+	}
+
+	if len(groups) == 0 {
+		fmt.Println("No external Go dependencies found.")
+		return nil
+	}
+
+	for _, group := range groups {
+		if group.License == "" {
+			fmt.Printf("⚠️  No detectable license (%d)\n", len(group.Modules))
+		} else {
+			fmt.Printf("%s (%d)\n", group.License, len(group.Modules))
+		}
+
+		for _, info := range group.Modules {
+			fmt.Printf("   %s", info.Module)
+			if info.Version != "" {
+				fmt.Printf(" (%s)", info.Version)
+			}
+			fmt.Printf(": %s", strings.Join(info.Projects, ", "))
+			if info.Reason != "" {
+				fmt.Printf(" [%s]", info.Reason)
+			}
+			fmt.Println()
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// goModCache returns the directory of the Go module cache
+func goModCache() (string, error) {
+	output, err := exec.Command("go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the Go module cache: %w", err)
+	}
+
+	modCache := strings.TrimSpace(string(output))
+	if modCache == "" {
+		return "", fmt.Errorf("failed to locate the Go module cache: GOMODCACHE is empty")
+	}
+	return modCache, nil
+}
+
+// moduleCacheDir returns the directory of modulePath@version in the module cache
+func moduleCacheDir(modCache, modulePath, version string) (string, error) {
+	if version == "" {
+		return "", fmt.Errorf("no version")
+	}
+
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return "", err
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(modCache, escapedPath+"@"+escapedVersion)
+	if _, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("not in module cache")
+	}
+	return dir, nil
+}
+
+// detectLicense identifies the license of the module in dir from its license
+// file. When none is detected it returns an empty license and the reason.
+func detectLicense(dir string) (string, string) {
+	for _, name := range licenseFileNames {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+
+		// Collapse whitespace so phrases match across line breaks
+		text := strings.Join(strings.Fields(string(data)), " ")
+		for _, pattern := range licensePatterns {
+			matched := true
+			for _, phrase := range pattern.phrases {
+				if !strings.Contains(text, phrase) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return pattern.license, ""
+			}
+		}
+		return "", fmt.Sprintf("unrecognized %s", name)
+	}
+
+	return "", "no license file"
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"duck/internal/dependencyscanner"
+	goscan "duck/internal/dependencyscanner/go"
+	jsscan "duck/internal/dependencyscanner/js"
+	"duck/internal/ignore"
+	"duck/internal/sbom"
+
+	"github.com/urfave/cli/v2"
+)
+
+// GenerateSBOM writes a CycloneDX document listing the external dependencies
+// of every project in the workspace
+func GenerateSBOM(c *cli.Context) error {
+	if outputFile := c.String("output"); outputFile != "" {
+		if err := checkWritable(outputFile); err != nil {
+			return err
+		}
+	}
+
+	absWorkspaceRoot, err := workspaceFlag(c)
+	if err != nil {
+		return err
+	}
+
+	originalCwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	if err := os.Chdir(absWorkspaceRoot); err != nil {
+		return fmt.Errorf("failed to change to workspace directory: %w", err)
+	}
+	defer os.Chdir(originalCwd)
+
+	projectConfig, allProjects, err := LoadProjectData()
+	if err != nil {
+		return fmt.Errorf("failed to load project data: %w", err)
+	}
+
+	registry, err := newDependencyRegistry(c.String("lang"), projectConfig.Ignore)
+	if err != nil {
+		return err
+	}
+
+	projectDirs := workspaceProjectDirs(absWorkspaceRoot, allProjects)
+	sort.Strings(projectDirs)
+
+	scanned, err := registry.ScanProjectsRecursive(absWorkspaceRoot, projectDirs)
+	if err != nil {
+		return fmt.Errorf("failed to scan dependencies: %w", err)
+	}
+
+	// Report projects by their path relative to the workspace root
+	graph := dependencyscanner.NewDependencyGraph()
+	for _, project := range scanned.GetProjectsWithDependencies() {
+		if relPath, err := filepath.Rel(absWorkspaceRoot, project.ProjectPath); err == nil {
+			project.ProjectPath = filepath.ToSlash(relPath)
+		}
+		graph.AddProject(project)
+	}
+
+	localPackages := localGoModules(allProjects)
+	bom := sbom.Build(graph, sbom.Options{
+		Name:        filepath.Base(absWorkspaceRoot),
+		ToolVersion: c.App.Version,
+		IsInternal: func(module string) bool {
+			return localPackages[module]
+		},
+	})
+
+	outputFile := c.String("output")
+	if outputFile == "" {
+		return sbom.Write(os.Stdout, bom)
+	}
+
+	// Resolve the output path against the directory duck was started in
+	if !filepath.IsAbs(outputFile) {
+		outputFile = filepath.Join(originalCwd, outputFile)
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create SBOM file: %w", err)
+	}
+	defer file.Close()
+
+	if err := sbom.Write(file, bom); err != nil {
+		return fmt.Errorf("failed to write SBOM: %w", err)
+	}
+
+	fmt.Printf("Wrote SBOM with %d components to %s\n", len(bom.Components), c.String("output"))
+	return nil
+}
+
+// newDependencyRegistry returns a registry with the scanners for lang, or all
+// scanners when lang is "all"
+func newDependencyRegistry(lang string, ignored *ignore.Matcher) (*dependencyscanner.ScannerRegistry, error) {
+	goScanner := goscan.NewGoScanner()
+	goScanner.SetIgnore(ignored)
+
+	scanners := []dependencyscanner.Scanner{goScanner, jsscan.NewJsScanner()}
+
+	registry := dependencyscanner.NewScannerRegistry()
+	var languages []string
+	registered := 0
+	for _, scanner := range scanners {
+		languages = append(languages, scanner.GetLanguage())
+		if lang == "all" || lang == scanner.GetLanguage() {
+			registry.RegisterScanner(scanner)
+			registered++
+		}
+	}
+
+	if registered == 0 {
+		return nil, fmt.Errorf("unsupported language '%s', use 'all' or one of: %s", lang, strings.Join(languages, ", "))
+	}
+
+	return registry, nil
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// projectRun is the outcome of running the script on one project
+type projectRun struct {
+	Key      string
+	Duration time.Duration
+	Success  bool
+	ExitCode int
+	// Interrupted is set when the run was stopped by a signal while the project ran
+	Interrupted bool
+	// SkippedBecause is the dependency that did not succeed when the project was skipped
+	SkippedBecause string
+}
+
+// printRunSummary renders a table of the projects of a run, in run order, with
+// their duration and status, followed by totals. With a positive threshold only
+// failed and skipped projects and projects that took longer than threshold are
+// listed; the totals always cover every project.
+func printRunSummary(w io.Writer, runs []projectRun, total int, threshold time.Duration) {
+	passedCount, failedCount, skippedCount, interruptedCount := 0, 0, 0, 0
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "  PROJECT\tDURATION\tSTATUS")
+
+	listed := 0
+	for _, run := range runs {
+		var status string
+		switch {
+		case run.SkippedBecause != "":
+			skippedCount++
+			status = colorWarning(fmt.Sprintf("⏭️  skipped (dependency %s did not succeed)", run.SkippedBecause))
+		case run.Interrupted:
+			interruptedCount++
+			status = colorWarning("⏹️  interrupted")
+		case run.Success:
+			passedCount++
+			status = colorSuccess("✅ passed")
+		default:
+			failedCount++
+			status = colorFailure(fmt.Sprintf("❌ failed (exit %d)", run.ExitCode))
+		}
+
+		if threshold > 0 && run.Success && run.Duration <= threshold {
+			continue
+		}
+
+		duration := "-"
+		if run.SkippedBecause == "" {
+			duration = run.Duration.Truncate(time.Millisecond).String()
+		}
+		fmt.Fprintf(table, "  %s\t%s\t%s\n", run.Key, duration, status)
+		listed++
+	}
+
+	fmt.Fprintln(w, "Summary:")
+	if listed > 0 {
+		table.Flush()
+	} else {
+		fmt.Fprintf(w, "  No failures and no project took longer than %v\n", threshold)
+	}
+
+	fmt.Fprintf(w, "  Total: %d passed, %d failed, %d skipped", passedCount, failedCount, skippedCount)
+	if interruptedCount > 0 {
+		fmt.Fprintf(w, ", %d interrupted", interruptedCount)
+	}
+	if notRun := total - len(runs); notRun > 0 {
+		fmt.Fprintf(w, ", %d not run", notRun)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"duck/internal/config"
+	"duck/internal/resolver"
+
+	"github.com/urfave/cli/v2"
+)
+
+func ShowTree(c *cli.Context) error {
+	_, projects, err := LoadProjectData()
+	if err != nil {
+		return err
+	}
+
+	inverse := c.Bool("inverse")
+	depth := c.Int("depth")
+	if depth < 0 {
+		return fmt.Errorf("depth must not be negative")
+	}
+
+	edges := dependencyEdges(projects)
+	if inverse {
+		edges = dependentEdges(projects)
+	}
+
+	var roots []string
+	if c.Args().Len() > 0 {
+		for _, name := range c.Args().Slice() {
+			projectKey, err := ResolveProjectKey(name, projects)
+			if err != nil {
+				return err
+			}
+			roots = append(roots, projectKey)
+		}
+	} else {
+		// Without arguments, start from every project nothing points at
+		reverse := dependentEdges(projects)
+		if inverse {
+			reverse = dependencyEdges(projects)
+		}
+		for key := range projects {
+			if len(reverse[key]) == 0 {
+				roots = append(roots, key)
+			}
+		}
+		sort.Strings(roots)
+	}
+
+	for i, root := range roots {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(root)
+		printTree(root, edges, depth, "", map[string]bool{root: true}, make(map[string]bool))
+	}
+
+	return nil
+}
+
+// printTree renders the children of key. Projects already expanded elsewhere in
+// the tree are marked with (*) instead of being repeated, and edges back into the
+// current path are marked as cycles.
+func printTree(key string, edges map[string][]string, maxDepth int, prefix string, path map[string]bool, expanded map[string]bool) {
+	children := edges[key]
+	expanded[key] = true
+
+	for i, child := range children {
+		connector, childPrefix := "├── ", "│   "
+		if i == len(children)-1 {
+			connector, childPrefix = "└── ", "    "
+		}
+
+		switch {
+		case path[child]:
+			fmt.Printf("%s%s%s (cycle)\n", prefix, connector, child)
+		case expanded[child] && len(edges[child]) > 0:
+			fmt.Printf("%s%s%s (*)\n", prefix, connector, child)
+		default:
+			fmt.Printf("%s%s%s\n", prefix, connector, child)
+			if maxDepth == 0 || len(path) < maxDepth {
+				path[child] = true
+				printTree(child, edges, maxDepth, prefix+childPrefix, path, expanded)
+				delete(path, child)
+			}
+		}
+	}
+}
+
+// dependencyEdges maps each project key to the sorted keys it depends on
+func dependencyEdges(projects map[string]*config.AppProject) map[string][]string {
+	edges := make(map[string][]string, len(projects))
+	for key, project := range projects {
+		deps := append([]string(nil), project.Config.Dependencies...)
+		sort.Strings(deps)
+		edges[key] = deps
+	}
+	return edges
+}
+
+// dependentEdges maps each project key to the sorted keys that depend on it
+func dependentEdges(projects map[string]*config.AppProject) map[string][]string {
+	r := resolver.New(projects)
+	edges := make(map[string][]string, len(projects))
+	for key := range projects {
+		edges[key] = r.GetDependents(key)
+	}
+	return edges
+}
@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"duck/internal/dependencyscanner"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// updateLookupWorkers is how many module lookups run concurrently
+const updateLookupWorkers = 8
+
+// ModuleUpdateInfo is the machine-readable form of an external Go dependency
+// and the newer versions published for it
+type ModuleUpdateInfo struct {
+	Module   string   `json:"module"`
+	Version  string   `json:"version,omitempty"`
+	Projects []string `json:"projects"`
+	// Latest is the newest release of the same major version, if newer
+	Latest string `json:"latest,omitempty"`
+	// MajorModule and MajorVersion are the newest release of a later major
+	// version, which for v2+ lives under another module path
+	MajorModule  string `json:"majorModule,omitempty"`
+	MajorVersion string `json:"majorVersion,omitempty"`
+	// Reason explains why the module could not be checked
+	Reason string `json:"reason,omitempty"`
+}
+
+// moduleUpdates is the result of looking up one module path
+type moduleUpdates struct {
+	latest       string
+	majorModule  string
+	majorVersion string
+	err          error
+}
+
+// printUpdates reports which external dependencies of the Go projects have
+// newer versions, as text or JSON. Lookups go through `go list`, so GOPROXY,
+// GOPRIVATE and GOFLAGS are honored.
+func printUpdates(projects []*dependencyscanner.ProjectDependencies, localPackages map[string]bool, jsonOutput bool, paths *pathFormatter) error {
+	modules := make(map[string]*ModuleUpdateInfo)
+	lookupDirs := make(map[string]string)
+	for _, project := range projects {
+		if project.Language != "go" {
+			continue
+		}
+
+		for _, dep := range project.Dependencies {
+			if localPackages[dep.Target] || dep.LocalPath != "" {
+				continue
+			}
+
+			id := dep.Target + "@" + dep.Version
+			info, exists := modules[id]
+			if !exists {
+				info = &ModuleUpdateInfo{Module: dep.Target, Version: dep.Version}
+				modules[id] = info
+			}
+			if _, exists := lookupDirs[dep.Target]; !exists {
+				lookupDirs[dep.Target] = project.ProjectPath
+			}
+
+			projectKey := paths.FormatKey(project.ProjectPath)
+			if len(info.Projects) == 0 || info.Projects[len(info.Projects)-1] != projectKey {
+				info.Projects = append(info.Projects, projectKey)
+			}
+		}
+	}
+
+	results := lookupModuleUpdates(lookupDirs)
+
+	infos := make([]ModuleUpdateInfo, 0, len(modules))
+	for _, info := range modules {
+		result := results[info.Module]
+		switch {
+		case result.err != nil:
+			info.Reason = result.err.Error()
+		case info.Version == "":
+			info.Reason = "no version"
+		default:
+			if semver.Compare(result.latest, info.Version) > 0 {
+				if semver.Major(result.latest) == semver.Major(info.Version) {
+					info.Latest = result.latest
+				} else {
+					// A +incompatible release of a later major version
+					info.MajorModule, info.MajorVersion = info.Module, result.latest
+				}
+			}
+			if result.majorModule != "" {
+				info.MajorModule, info.MajorVersion = result.majorModule, result.majorVersion
+			}
+		}
+		infos = append(infos, *info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Module != infos[j].Module {
+			return infos[i].Module < infos[j].Module
+		}
+		return semver.Compare(infos[i].Version, infos[j].Version) < 0
+	})
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(infos)
+	}
+
+	if len(infos) == 0 {
+		fmt.Println("No external Go dependencies found.")
+		return nil
+	}
+
+	var minor, major, unchecked []ModuleUpdateInfo
+	upToDate := 0
+	for _, info := range infos {
+		if info.Reason != "" {
+			unchecked = append(unchecked, info)
+			continue
+		}
+		if info.Latest != "" {
+			minor = append(minor, info)
+		}
+		if info.MajorModule != "" {
+			major = append(major, info)
+		}
+		if info.Latest == "" && info.MajorModule == "" {
+			upToDate++
+		}
+	}
+
+	if len(minor) > 0 {
+		fmt.Printf("Updates available (%d)\n", len(minor))
+		for _, info := range minor {
+			fmt.Printf("   %s %s → %s: %s\n", info.Module, info.Version, info.Latest, strings.Join(info.Projects, ", "))
+		}
+		fmt.Println()
+	}
+
+	if len(major) > 0 {
+		fmt.Printf("⚠️  New major versions (%d)\n", len(major))
+		for _, info := range major {
+			fmt.Printf("   %s %s → %s %s: %s\n", info.Module, info.Version, info.MajorModule, info.MajorVersion, strings.Join(info.Projects, ", "))
+		}
+		fmt.Println()
+	}
+
+	if len(unchecked) > 0 {
+		fmt.Printf("⚠️  Could not check (%d)\n", len(unchecked))
+		for _, info := range unchecked {
+			fmt.Printf("   %s", info.Module)
+			if info.Version != "" {
+				fmt.Printf(" (%s)", info.Version)
+			}
+			fmt.Printf(": %s [%s]\n", strings.Join(info.Projects, ", "), info.Reason)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("✅ %d of %d module(s) up to date\n", upToDate, len(infos))
+	return nil
+}
+
+// lookupModuleUpdates looks up the newest versions of every module path in
+// lookupDirs, running `go list` in the directory of a project requiring it
+func lookupModuleUpdates(lookupDirs map[string]string) map[string]moduleUpdates {
+	results := make(map[string]moduleUpdates, len(lookupDirs))
+
+	// Without a module proxy nothing can be looked up, so skip the lookups
+	if proxy, err := exec.Command("go", "env", "GOPROXY").Output(); err == nil && strings.TrimSpace(string(proxy)) == "off" {
+		for modulePath := range lookupDirs {
+			results[modulePath] = moduleUpdates{err: errors.New("module lookup disabled by GOPROXY=off")}
+		}
+		return results
+	}
+
+	var mu sync.Mutex
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < updateLookupWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for modulePath := range jobs {
+				result := lookupModule(lookupDirs[modulePath], modulePath)
+				mu.Lock()
+				results[modulePath] = result
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for modulePath := range lookupDirs {
+		jobs <- modulePath
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// lookupModule finds the latest version of modulePath and of the latest major
+// version published after it
+func lookupModule(dir, modulePath string) moduleUpdates {
+	latest, err := latestModuleVersion(dir, modulePath)
+	if err != nil {
+		return moduleUpdates{err: err}
+	}
+
+	result := moduleUpdates{latest: latest}
+	for next := nextMajorPath(modulePath); next != ""; next = nextMajorPath(next) {
+		version, err := latestModuleVersion(dir, next)
+		if err != nil {
+			break
+		}
+		result.majorModule, result.majorVersion = next, version
+	}
+	return result
+}
+
+// latestModuleVersion returns the version `go list` resolves modulePath@latest to
+func latestModuleVersion(dir, modulePath string) (string, error) {
+	cmd := exec.Command("go", "list", "-m", "-f", "{{.Version}}", modulePath+"@latest")
+	cmd.Dir = dir
+	// Version queries are not allowed with -mod=vendor
+	cmd.Env = append(os.Environ(), "GOFLAGS="+strings.TrimSpace(os.Getenv("GOFLAGS")+" -mod=mod"))
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			message, _, _ = strings.Cut(message, "\n")
+			message = strings.TrimPrefix(message, "go: ")
+			return "", errors.New(strings.TrimPrefix(message, "module "+modulePath+": "))
+		}
+		return "", fmt.Errorf("go list failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// nextMajorPath returns the module path of the major version after the one of
+// modulePath, or "" if modulePath is invalid
+func nextMajorPath(modulePath string) string {
+	prefix, pathMajor, ok := module.SplitPathVersion(modulePath)
+	if !ok {
+		return ""
+	}
+
+	// gopkg.in paths always carry their major version as a .vN suffix
+	if strings.HasPrefix(pathMajor, ".v") {
+		major, _ := strconv.Atoi(pathMajor[2:])
+		return fmt.Sprintf("%s.v%d", prefix, major+1)
+	}
+
+	major := 1
+	if pathMajor != "" {
+		major, _ = strconv.Atoi(strings.TrimPrefix(pathMajor, "/v"))
+	}
+	return fmt.Sprintf("%s/v%d", prefix, major+1)
+}
@@ -1,31 +1,180 @@
 package cli
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"duck/internal/config"
+	"duck/internal/executor"
+	"duck/internal/filter"
+	"duck/internal/resolver"
 	"duck/internal/scanner"
+	"duck/internal/state"
+
+	"gopkg.in/yaml.v3"
 )
 
+// GlobalOptions holds the values of global flags that affect every command
+type GlobalOptions struct {
+	NoCache          bool
+	CacheScan        bool
+	NoDefaultIgnores bool
+	// ReadOnly makes commands refuse to write files; the scan cache is still
+	// read, and run state is not recorded
+	ReadOnly bool
+	// ConfigFile is the workspace config, relative to the workspace root duck runs in
+	ConfigFile string
+	// InvocationDir is the directory duck was started in, before it changed to
+	// the workspace root
+	InvocationDir string
+}
+
+var globalOptions GlobalOptions
+
+// enterWorkspaceRoot changes to the directory of configPath, or without it to
+// the nearest directory at or above the current one containing duck.yaml. If
+// there is none, duck stays where it is and commands report the missing
+// duck.yaml as usual.
+func enterWorkspaceRoot(configPath string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	globalOptions.InvocationDir = cwd
+
+	var root string
+	if configPath != "" {
+		absPath, err := filepath.Abs(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve --config: %w", err)
+		}
+		if info, err := os.Stat(absPath); err != nil || info.IsDir() {
+			return fmt.Errorf("config file %s was not found", configPath)
+		}
+		root = filepath.Dir(absPath)
+		globalOptions.ConfigFile = filepath.Base(absPath)
+	} else if root, err = config.FindWorkspaceRoot(); err != nil {
+		return nil
+	}
+
+	if err := os.Chdir(root); err != nil {
+		return fmt.Errorf("failed to change to workspace root %s: %w", root, err)
+	}
+	return nil
+}
+
+// checkWritable returns an error naming what would have been written when duck
+// runs with --read-only
+func checkWritable(what string) error {
+	if globalOptions.ReadOnly {
+		return fmt.Errorf("write to %s blocked by --read-only", what)
+	}
+	return nil
+}
+
 type FilterOptions struct {
 	Namespace string
 	Tags      []string
+	// Match, when set, must also accept a project for it to be kept
+	Match ProjectPredicate
+}
+
+// ProjectPredicate reports whether the project stored under key is selected
+type ProjectPredicate func(key string, project *config.AppProject) bool
+
+// ProjectInfo is the machine-readable representation of a project used by
+// the structured output formats
+type ProjectInfo struct {
+	Key          string   `json:"key" yaml:"key"`
+	Name         string   `json:"name" yaml:"name"`
+	Namespace    string   `json:"namespace" yaml:"namespace"`
+	Aliases      []string `json:"aliases" yaml:"aliases"`
+	Description  string   `json:"description" yaml:"description"`
+	Tags         []string `json:"tags" yaml:"tags"`
+	Dependencies []string `json:"dependencies" yaml:"dependencies"`
+	Path         string   `json:"path" yaml:"path"`
 }
 
 func LoadProjectData() (*config.ProjectConfig, map[string]*config.AppProject, error) {
-	projectConfig, err := config.LoadProjectConfig("duck.yaml")
+	projectConfig, scanner, err := loadProjectScanner()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if projectConfig.DefaultTargetDirectory {
+		fmt.Fprintf(os.Stderr, "Warning: duck.yaml does not set targetDirectory; scanning the whole workspace root\n")
+	}
+
+	for _, err := range scanner.DuplicateKeyErrors() {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	duplicates := scanner.GetDuplicateNames()
+	var duplicateNames []string
+	for name := range duplicates {
+		duplicateNames = append(duplicateNames, name)
+	}
+	sort.Strings(duplicateNames)
+	for _, name := range duplicateNames {
+		fmt.Fprintf(os.Stderr, "Warning: Project name or alias '%s' is used by multiple projects: %s\n", name, strings.Join(duplicates[name], ", "))
+	}
+
+	return projectConfig, scanner.GetProjects(), nil
+}
+
+// runPreScan runs the preScan command of duck.yaml in the workspace root. Its
+// output goes to stderr so that it does not mix with machine-readable output.
+func runPreScan(projectConfig *config.ProjectConfig) error {
+	if projectConfig.PreScan == "" {
+		return nil
+	}
+
+	shell, err := executor.LookupShell(projectConfig.Shell)
+	if err != nil {
+		return fmt.Errorf("preScan: %w", err)
+	}
+
+	args := append(append([]string(nil), shell[1:]...), projectConfig.PreScan)
+	cmd := exec.Command(shell[0], args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("preScan command failed: %w", err)
+	}
+
+	return nil
+}
+
+// loadProjectScanner loads duck.yaml and returns a scanner that has already
+// scanned the workspace, for callers that need more than the project map
+func loadProjectScanner() (*config.ProjectConfig, *scanner.Scanner, error) {
+	projectConfig, err := config.LoadProjectConfigWithOptions(globalOptions.ConfigFile, config.LoadOptions{
+		NoDefaultIgnores: globalOptions.NoDefaultIgnores,
+	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to load project config: %w", err)
 	}
 
+	if err := runPreScan(projectConfig); err != nil {
+		return nil, nil, err
+	}
+
 	scanner := scanner.New(projectConfig)
+	scanner.SetCacheEnabled((projectConfig.ScanCache || globalOptions.CacheScan) && !globalOptions.NoCache)
+	scanner.SetCacheReadOnly(globalOptions.ReadOnly)
 	if err := scanner.ScanProjects(); err != nil {
 		return nil, nil, fmt.Errorf("failed to scan projects: %w", err)
 	}
 
-	return projectConfig, scanner.GetProjects(), nil
+	return projectConfig, scanner, nil
 }
 
 func FilterProjects(projects map[string]*config.AppProject, opts FilterOptions) map[string]*config.AppProject {
@@ -56,6 +205,10 @@ func FilterProjects(projects map[string]*config.AppProject, opts FilterOptions)
 			}
 		}
 
+		if opts.Match != nil && !opts.Match(key, project) {
+			continue
+		}
+
 		filtered[key] = project
 	}
 
@@ -78,6 +231,10 @@ func LoadProjectConfig(configPath string) (*config.ProjectConfig, error) {
 }
 
 func UpdateProjectConfigFormat(configPath string, format string) error {
+	if err := checkWritable(configPath); err != nil {
+		return err
+	}
+
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %w", err)
@@ -102,7 +259,7 @@ func UpdateProjectConfigFormat(configPath string, format string) error {
 				newLines := make([]string, 0, len(lines)+3)
 				newLines = append(newLines, lines[:i+1]...)
 				newLines = append(newLines, "")
-				newLines = append(newLines, "# Project configuration format: \"duck\" or \"nx\"")
+				newLines = append(newLines, "# Project configuration format: \"duck\", \"nx\", \"toml\", \"all\", or \"all+toml\"")
 				newLines = append(newLines, fmt.Sprintf("projectConfigFormat: \"%s\"", format))
 				newLines = append(newLines, lines[i+1:]...)
 				lines = newLines
@@ -122,18 +279,485 @@ func UpdateProjectConfigFormat(configPath string, format string) error {
 // ResolveProjectKey resolves a project name or key to the actual project key
 // This allows users to reference projects by their name (e.g., "sending-api")
 // or by their path (e.g., "core-event/sending-api")
-func ResolveProjectKey(projectIdentifier string, projects map[string]*config.AppProject) (string, bool) {
+// A name shared by several projects is ambiguous and must be referenced by its full key
+func ResolveProjectKey(projectIdentifier string, projects map[string]*config.AppProject) (string, error) {
 	// First, check if it's a direct key match (path-based)
 	if _, exists := projects[projectIdentifier]; exists {
-		return projectIdentifier, true
+		return projectIdentifier, nil
 	}
 
-	// If not found, try to find by project name
+	// If not found, try to find by project name or alias
+	var candidates []string
 	for key, project := range projects {
-		if project.Config.Name == projectIdentifier {
-			return key, true
+		if slices.Contains(project.Config.Names(), projectIdentifier) {
+			candidates = append(candidates, key)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("project '%s' not found", projectIdentifier)
+	case 1:
+		return candidates[0], nil
+	}
+
+	sort.Strings(candidates)
+	return "", fmt.Errorf("ambiguous project name or alias '%s', use the full key: %s", projectIdentifier, strings.Join(candidates, ", "))
+}
+
+// NewProjectInfos converts projects into ProjectInfo values sorted by project key
+// so that structured output stays stable between runs
+func NewProjectInfos(projects map[string]*config.AppProject, paths *pathFormatter) []ProjectInfo {
+	keys := make([]string, 0, len(projects))
+	for key := range projects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	infos := make([]ProjectInfo, 0, len(keys))
+	for _, key := range keys {
+		project := projects[key]
+		info := ProjectInfo{
+			Key:          key,
+			Name:         project.Config.Name,
+			Namespace:    project.Config.Namespace,
+			Aliases:      project.Config.Aliases,
+			Description:  project.Config.Description,
+			Tags:         project.Config.Tags,
+			Dependencies: project.Config.Dependencies,
+			Path:         paths.Format(project.Path),
+		}
+		if info.Aliases == nil {
+			info.Aliases = []string{}
+		}
+		if info.Tags == nil {
+			info.Tags = []string{}
+		}
+		if info.Dependencies == nil {
+			info.Dependencies = []string{}
 		}
+		infos = append(infos, info)
 	}
 
-	return "", false
+	return infos
+}
+
+// printProjects writes projects to stdout in the given structured format ("json" or "yaml")
+func printProjects(projects map[string]*config.AppProject, format string, paths *pathFormatter) error {
+	infos := NewProjectInfos(projects, paths)
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(infos)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(infos)
+	}
+
+	return fmt.Errorf("unsupported output format: %s", format)
+}
+
+// writeProjectSelection writes the given project keys to path, one key per line,
+// so a later invocation can reuse the selection via --projects-from-file
+func writeProjectSelection(path string, projectKeys []string) error {
+	if err := checkWritable(path); err != nil {
+		return err
+	}
+
+	var builder strings.Builder
+	for _, key := range projectKeys {
+		builder.WriteString(key)
+		builder.WriteString("\n")
+	}
+
+	if err := os.WriteFile(path, []byte(builder.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write project selection: %w", err)
+	}
+
+	return nil
+}
+
+// readProjectSelection reads project keys (one per line) written by --projects-output.
+// Blank lines and lines starting with '#' are ignored; the file order is preserved.
+func readProjectSelection(path string, projects map[string]*config.AppProject) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project selection: %w", err)
+	}
+	defer file.Close()
+
+	var projectKeys []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		projectKey, err := ResolveProjectKey(line, projects)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if !seen[projectKey] {
+			seen[projectKey] = true
+			projectKeys = append(projectKeys, projectKey)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read project selection: %w", err)
+	}
+
+	return projectKeys, nil
+}
+
+// ProjectSelectionEntry is one project of a --projects-json selection, with
+// optional overrides applied to that project only
+type ProjectSelectionEntry struct {
+	Key         string            `json:"key"`
+	Environment map[string]string `json:"env,omitempty"`
+	// Timeout is a duration such as "30s"
+	Timeout string `json:"timeout,omitempty"`
+	Retries *int   `json:"retries,omitempty"`
+}
+
+// projectsFromJSON parses a --projects-json selection, resolves its keys, and
+// applies its overrides to the loaded configs of the selected projects as
+// scriptSettings of script and project environment. The keys are returned in
+// dependency order.
+func projectsFromJSON(raw, script string, projects map[string]*config.AppProject) ([]string, error) {
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+
+	var entries []ProjectSelectionEntry
+	if err := decoder.Decode(&entries); err != nil {
+		return nil, fmt.Errorf("invalid --projects-json: %w", err)
+	}
+
+	selected := make(map[string]bool, len(entries))
+	var projectKeys []string
+	for i, entry := range entries {
+		if entry.Key == "" {
+			return nil, fmt.Errorf("invalid --projects-json: entry %d has no key", i)
+		}
+		projectKey, err := ResolveProjectKey(entry.Key, projects)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --projects-json: %w", err)
+		}
+		if selected[projectKey] {
+			return nil, fmt.Errorf("invalid --projects-json: project %s is listed twice", projectKey)
+		}
+		selected[projectKey] = true
+		projectKeys = append(projectKeys, projectKey)
+
+		var timeout time.Duration
+		if entry.Timeout != "" {
+			timeout, err = time.ParseDuration(entry.Timeout)
+			if err != nil || timeout < 0 {
+				return nil, fmt.Errorf("invalid --projects-json: %s: timeout must be a non-negative duration, got %q", projectKey, entry.Timeout)
+			}
+		}
+		if entry.Retries != nil && *entry.Retries < 0 {
+			return nil, fmt.Errorf("invalid --projects-json: %s: retries must not be negative", projectKey)
+		}
+
+		appConfig := projects[projectKey].Config
+		if len(entry.Environment) > 0 {
+			environment := make(map[string]string, len(appConfig.Environment)+len(entry.Environment))
+			for key, value := range appConfig.Environment {
+				environment[key] = value
+			}
+			for key, value := range entry.Environment {
+				environment[key] = value
+			}
+			appConfig.Environment = environment
+		}
+		if timeout > 0 || entry.Retries != nil {
+			settings := appConfig.ScriptSettings[script]
+			if timeout > 0 {
+				settings.Timeout = timeout
+			}
+			if entry.Retries != nil {
+				settings.Retries = entry.Retries
+			}
+			if appConfig.ScriptSettings == nil {
+				appConfig.ScriptSettings = make(map[string]config.ScriptSettings)
+			}
+			appConfig.ScriptSettings[script] = settings
+		}
+	}
+
+	resolution, err := resolver.New(projects).ResolveForTargets(projectKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve dependencies: %w", err)
+	}
+
+	var ordered []string
+	for _, key := range resolution.ExecutionOrder {
+		if selected[key] {
+			ordered = append(ordered, key)
+		}
+	}
+	return ordered, nil
+}
+
+// failedLastRun returns the projects whose most recent run of script failed,
+// as recorded in the workspace's run state
+func failedLastRun(script string, projects map[string]*config.AppProject) ([]string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	runState, err := state.Load(cwd)
+	if err != nil {
+		return nil, err
+	}
+
+	var projectKeys []string
+	for _, key := range runState.FailedProjects(script) {
+		if _, exists := projects[key]; !exists {
+			fmt.Fprintf(os.Stderr, "Warning: Project '%s' failed last run but no longer exists\n", key)
+			continue
+		}
+		projectKeys = append(projectKeys, key)
+	}
+
+	return projectKeys, nil
+}
+
+// recordRunResults stores the outcome of a run, and the durations of the
+// projects that passed, in the workspace's run state. Failing to record state
+// only produces a warning, and nothing is recorded with --read-only.
+func recordRunResults(script string, passed, failed *[]string, durations map[string]time.Duration) {
+	if len(*passed) == 0 && len(*failed) == 0 || globalOptions.ReadOnly {
+		return
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record run state: %v\n", err)
+		return
+	}
+
+	runState, err := state.Load(cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+
+	runState.RecordResults(script, *passed, *failed)
+	runState.RecordDurations(script, durations)
+	if err := runState.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// loadRunEnvironment builds the command-line environment layer for a run.
+// Env files are applied in the order given and --env KEY=VALUE pairs override them.
+func loadRunEnvironment(envFiles []string, envPairs []string) (map[string]string, error) {
+	environment := make(map[string]string)
+
+	for _, path := range envFiles {
+		fileEnv, err := config.LoadEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fileEnv {
+			environment[key] = value
+		}
+	}
+
+	for _, pair := range envPairs {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid --env value '%s': expected KEY=VALUE", pair)
+		}
+		environment[key] = value
+	}
+
+	return environment, nil
+}
+
+const (
+	PathStyleRelative = "relative"
+	PathStyleAbsolute = "absolute"
+)
+
+// pathFormatter renders project paths in the style selected with --path-style.
+// Relative paths are computed against the canonical workspace root.
+type pathFormatter struct {
+	style         string
+	workspaceRoot string
+}
+
+func newPathFormatter(style string) (*pathFormatter, error) {
+	if style == "" {
+		style = PathStyleRelative
+	}
+	if style != PathStyleRelative && style != PathStyleAbsolute {
+		return nil, fmt.Errorf("invalid path style: must be '%s' or '%s', got '%s'", PathStyleRelative, PathStyleAbsolute, style)
+	}
+
+	workspaceRoot, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	return &pathFormatter{style: style, workspaceRoot: workspaceRoot}, nil
+}
+
+// Format renders an absolute project path
+func (f *pathFormatter) Format(path string) string {
+	if f.style == PathStyleAbsolute {
+		return path
+	}
+
+	rel, err := filepath.Rel(canonicalPath(f.workspaceRoot), canonicalPath(path))
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// FormatKey renders a path given relative to the workspace root, such as a project key
+func (f *pathFormatter) FormatKey(relPath string) string {
+	if f.style == PathStyleAbsolute {
+		return filepath.Join(f.workspaceRoot, relPath)
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// canonicalPath resolves symlinks so paths reached through different links compare equal
+func canonicalPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// filterByDependencies keeps the projects in filtered that transitively depend on
+// dependsOn and that dependedBy transitively depends on. Empty arguments are ignored.
+func filterByDependencies(filtered, projects map[string]*config.AppProject, dependsOn, dependedBy string) (map[string]*config.AppProject, error) {
+	r := resolver.New(projects)
+
+	keep := func(keys []string) {
+		allowed := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			allowed[key] = true
+		}
+		for key := range filtered {
+			if !allowed[key] {
+				delete(filtered, key)
+			}
+		}
+	}
+
+	if dependsOn != "" {
+		projectKey, err := ResolveProjectKey(dependsOn, projects)
+		if err != nil {
+			return nil, err
+		}
+		keep(r.GetTransitiveDependents(projectKey))
+	}
+
+	if dependedBy != "" {
+		projectKey, err := ResolveProjectKey(dependedBy, projects)
+		if err != nil {
+			return nil, err
+		}
+		keep(r.GetTransitiveDependencies(projectKey))
+	}
+
+	return filtered, nil
+}
+
+// compileFilter compiles a --filter expression into a predicate over projects.
+// A project the expression fails to evaluate for is reported on stderr and not
+// selected.
+func compileFilter(expression string, projects map[string]*config.AppProject) (ProjectPredicate, error) {
+	f, err := filter.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	r := resolver.New(projects)
+
+	return func(key string, project *config.AppProject) bool {
+		ok, err := f.Match(filter.Project{
+			Name:       project.Config.Name,
+			Namespace:  project.Config.Namespace,
+			Tags:       project.Config.Tags,
+			Deps:       project.Config.Dependencies,
+			Dependents: r.GetDependents(key),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return false
+		}
+		return ok
+	}, nil
+}
+
+// filterChangedProjects keeps the projects in filtered that contain uncommitted
+// changes, staged or not, in the git working tree of the workspace
+func filterChangedProjects(filtered map[string]*config.AppProject) (map[string]*config.AppProject, error) {
+	workspaceRoot, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	files, err := gitDirtyFiles(workspaceRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed files: %w", err)
+	}
+
+	return projectsContaining(workspaceRoot, filtered, files), nil
+}
+
+// filterProjectsChangedSince keeps the projects in filtered that contain files
+// changed by the git commits made since a point in time (see gitFilesChangedSince)
+func filterProjectsChangedSince(filtered map[string]*config.AppProject, since string) (map[string]*config.AppProject, error) {
+	workspaceRoot, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	files, err := gitFilesChangedSince(workspaceRoot, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files changed since %s: %w", since, err)
+	}
+
+	return projectsContaining(workspaceRoot, filtered, files), nil
+}
+
+// projectsContaining returns the projects of projects (keyed by their path
+// relative to workspaceRoot) whose directory contains one of files
+func projectsContaining(workspaceRoot string, projects map[string]*config.AppProject, files []string) map[string]*config.AppProject {
+	keys := make([]string, 0, len(projects))
+	for key := range projects {
+		keys = append(keys, key)
+	}
+
+	containing := make(map[string]*config.AppProject)
+	for _, key := range changedProjectDirs(workspaceRoot, keys, files) {
+		containing[key] = projects[key]
+	}
+	return containing
+}
+
+// isTerminal reports whether file is a terminal rather than a pipe or a file
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"duck/internal/config"
+	"duck/internal/resolver"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ValidationIssue is a single problem found while validating the workspace
+type ValidationIssue struct {
+	Subject string // Project key or config file path the issue refers to
+	Message string
+	Warning bool
+}
+
+func ValidateConfig(c *cli.Context) error {
+	projectConfig, scanner, err := loadProjectScanner()
+	if err != nil {
+		return err
+	}
+
+	issues := validateWorkspace(projectConfig, scanner.GetProjects(), scanner.GetLoadErrors(), scanner.GetDuplicateKeys(), scanner.GetDuplicateNames())
+	if c.Bool("check-scripts") {
+		issues = append(issues, checkScripts(projectConfig.Scripts)...)
+		sortIssues(issues)
+	}
+
+	strict := c.Bool("strict")
+	errorCount, warningCount := 0, 0
+
+	for _, issue := range issues {
+		if issue.Warning && !strict {
+			fmt.Printf("⚠️  %s: %s\n", issue.Subject, issue.Message)
+			warningCount++
+		} else {
+			fmt.Printf("❌ %s: %s\n", issue.Subject, issue.Message)
+			errorCount++
+		}
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("validation failed: %d error(s), %d warning(s)", errorCount, warningCount)
+	}
+
+	if warningCount > 0 {
+		fmt.Printf("\n✅ Configuration is valid (%d warning(s))\n", warningCount)
+	} else {
+		fmt.Printf("✅ Configuration is valid (%d project(s) checked)\n", len(scanner.GetProjects()))
+	}
+
+	return nil
+}
+
+// validateWorkspace checks the loaded configuration for problems.
+// Issues are returned sorted by subject so the report is stable.
+func validateWorkspace(projectConfig *config.ProjectConfig, projects map[string]*config.AppProject, loadErrors map[string]error, duplicateKeys, duplicateNames map[string][]string) []ValidationIssue {
+	var issues []ValidationIssue
+
+	if projectConfig.DefaultTargetDirectory {
+		issues = append(issues, ValidationIssue{Subject: "duck.yaml", Message: "targetDirectory is not set; the whole workspace root is scanned", Warning: true})
+	}
+
+	for path, err := range loadErrors {
+		issues = append(issues, ValidationIssue{Subject: path, Message: err.Error()})
+	}
+
+	for key, files := range duplicateKeys {
+		issues = append(issues, ValidationIssue{
+			Subject: key,
+			Message: fmt.Sprintf("project key is claimed by multiple config files (%s); only %s is used", strings.Join(files, ", "), files[0]),
+		})
+	}
+
+	for name, keys := range duplicateNames {
+		for _, key := range keys {
+			issues = append(issues, ValidationIssue{
+				Subject: key,
+				Message: fmt.Sprintf("project name or alias '%s' is shared with other projects (%s)", name, strings.Join(keys, ", ")),
+				Warning: true,
+			})
+		}
+	}
+
+	// Projects with their unknown dependencies stripped, used for cycle detection
+	known := make(map[string]*config.AppProject, len(projects))
+
+	for key, project := range projects {
+		if project.Config.Name == "" {
+			issues = append(issues, ValidationIssue{Subject: key, Message: "missing required field 'name'"})
+		}
+		if project.Config.Namespace == "" {
+			issues = append(issues, ValidationIssue{Subject: key, Message: "missing required field 'namespace'"})
+		}
+		if project.Config.Description == "" {
+			issues = append(issues, ValidationIssue{Subject: key, Message: "empty description", Warning: true})
+		}
+
+		var knownDeps []string
+		for _, dep := range project.Config.Dependencies {
+			if dep == key {
+				issues = append(issues, ValidationIssue{Subject: key, Message: "project depends on itself"})
+				continue
+			}
+			if _, exists := projects[dep]; !exists {
+				issues = append(issues, ValidationIssue{Subject: key, Message: fmt.Sprintf("depends on %s, but %s was not found", dep, dep)})
+				continue
+			}
+			knownDeps = append(knownDeps, dep)
+		}
+
+		for scriptName := range project.Config.Scripts {
+			if _, exists := projectConfig.Scripts[scriptName]; !exists {
+				issues = append(issues, ValidationIssue{Subject: key, Message: fmt.Sprintf("script '%s' is not defined in duck.yaml", scriptName)})
+			}
+		}
+
+		appConfig := *project.Config
+		appConfig.Dependencies = knownDeps
+		known[key] = &config.AppProject{Config: &appConfig, Path: project.Path}
+	}
+
+	if err := resolver.New(known).ValidateDependencies(); err != nil {
+		issues = append(issues, ValidationIssue{Subject: "dependencies", Message: err.Error()})
+	}
+
+	sortIssues(issues)
+	return issues
+}
+
+// sortIssues orders issues by subject, then message
+func sortIssues(issues []ValidationIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Subject != issues[j].Subject {
+			return issues[i].Subject < issues[j].Subject
+		}
+		return issues[i].Message < issues[j].Message
+	})
+}
+
+// shBuiltins are commands that sh provides itself, so they are not looked up on PATH
+var shBuiltins = map[string]bool{
+	".": true, ":": true, "[": true, "alias": true, "break": true, "case": true, "cd": true,
+	"command": true, "continue": true, "do": true, "done": true, "elif": true, "else": true,
+	"esac": true, "eval": true, "exec": true, "exit": true, "export": true, "false": true,
+	"fi": true, "for": true, "if": true, "read": true, "readonly": true, "return": true,
+	"set": true, "shift": true, "test": true, "then": true, "trap": true, "true": true,
+	"umask": true, "unset": true, "until": true, "wait": true, "while": true,
+	"echo": true, "printf": true, "pwd": true, "{": true, "}": true, "(": true, "!": true,
+}
+
+// bashisms are shell features that sh (which runs scripts) does not support
+var bashisms = []struct {
+	pattern *regexp.Regexp
+	feature string
+}{
+	{regexp.MustCompile(`\[\[`), "[[ ... ]] tests"},
+	{regexp.MustCompile(`(^|[;&|\s])function\s+\w+`), "the function keyword"},
+	{regexp.MustCompile(`(^|[;&|\s])source\s`), "source (use . instead)"},
+	{regexp.MustCompile(`[<>]\(`), "process substitution"},
+	{regexp.MustCompile(`&>`), "&> redirection (use >file 2>&1 instead)"},
+	{regexp.MustCompile(`\$\{\w+//?[^}]*/`), "${var/pattern/replacement}"},
+	{regexp.MustCompile(`\w+=\(`), "arrays"},
+	{regexp.MustCompile(`\$'`), "$'...' strings"},
+}
+
+// redirection matches redirections such as 2>&1 and &>file whose & does not separate commands
+var redirection = regexp.MustCompile(`\d*[<>]&\d*-?|&>>?`)
+
+// commandSeparator splits a command line into simple commands
+var commandSeparator = regexp.MustCompile(`&&|\|\||[;|&\n]`)
+
+// checkScripts lints the commands of the scripts in duck.yaml for common
+// mistakes. All findings are warnings.
+func checkScripts(scripts map[string]config.Script) []ValidationIssue {
+	var issues []ValidationIssue
+
+	for name, script := range scripts {
+		subject := fmt.Sprintf("script '%s'", name)
+		warn := func(format string, args ...interface{}) {
+			issues = append(issues, ValidationIssue{Subject: subject, Message: fmt.Sprintf(format, args...), Warning: true})
+		}
+
+		// Steps are separate commands, like the lines of a script
+		command := strings.TrimSpace(strings.Join(script.Steps(), "\n"))
+		if command == "" {
+			warn("command is empty")
+			continue
+		}
+
+		for _, bashism := range bashisms {
+			if bashism.pattern.MatchString(command) {
+				warn("uses %s, which sh does not support", bashism.feature)
+			}
+		}
+
+		missing := make(map[string]bool)
+		for _, part := range commandSeparator.Split(redirection.ReplaceAllString(command, " "), -1) {
+			program := firstProgram(part)
+			if program == "" {
+				continue
+			}
+
+			if program == "cd" {
+				warn("changes directory with cd; set workingDir instead")
+				continue
+			}
+
+			// Bash-only commands are already reported as bashisms
+			if shBuiltins[program] || program == "[[" || program == "source" || program == "function" {
+				continue
+			}
+			if missing[program] || strings.ContainsAny(program, "/{$`\"'<>") {
+				continue
+			}
+			if _, err := exec.LookPath(program); err != nil {
+				missing[program] = true
+				warn("executable '%s' was not found on PATH", program)
+			}
+		}
+	}
+
+	return issues
+}
+
+// firstProgram returns the program a simple command runs, skipping leading
+// variable assignments
+func firstProgram(command string) string {
+	for _, field := range strings.Fields(command) {
+		if strings.Contains(field, "=") && !strings.HasPrefix(field, "=") {
+			continue
+		}
+		return strings.TrimLeft(field, "(")
+	}
+	return ""
+}
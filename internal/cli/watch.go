@@ -0,0 +1,308 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"duck/internal/config"
+	"duck/internal/executor"
+	"duck/internal/ignore"
+	"duck/internal/resolver"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/urfave/cli/v2"
+)
+
+// WatchProjects runs a script on the selected projects, then watches them and
+// their transitive dependencies and re-runs the script on the selected
+// projects affected by each batch of file changes until interrupted
+func WatchProjects(c *cli.Context) error {
+	projectConfig, projects, err := LoadProjectData()
+	if err != nil {
+		return err
+	}
+
+	scriptName := c.String("script")
+	script, exists := projectConfig.Scripts[scriptName]
+	if !exists {
+		return fmt.Errorf("script '%s' not found", scriptName)
+	}
+	if err := checkServiceWritable(script); err != nil {
+		return err
+	}
+
+	debounce := c.Duration("debounce")
+	if debounce < 0 {
+		return fmt.Errorf("--debounce must not be negative")
+	}
+
+	targetProjects, err := selectTargetProjects(c, projects)
+	if err != nil {
+		return err
+	}
+	if len(targetProjects) == 0 {
+		fmt.Println("No projects match the selection criteria.")
+		return nil
+	}
+
+	r := resolver.New(projects)
+	closure, err := r.ResolveForTargets(targetProjects)
+	if err != nil {
+		return fmt.Errorf("failed to resolve dependencies: %w", err)
+	}
+
+	// Run targets in dependency order
+	isTarget := make(map[string]bool, len(targetProjects))
+	for _, key := range targetProjects {
+		isTarget[key] = true
+	}
+	var ordered []string
+	for _, key := range closure.ExecutionOrder {
+		if isTarget[key] {
+			ordered = append(ordered, key)
+		}
+	}
+
+	workspaceRoot, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	projectDirs := make(map[string]string, len(closure.ExecutionOrder))
+	for _, key := range closure.ExecutionOrder {
+		projectDirs[projects[key].Path] = key
+		if err := watchTree(watcher, projects[key].Path, projectConfig.Ignore); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", key, err)
+		}
+	}
+	w := &projectWatcher{
+		watcher:     watcher,
+		ignored:     projectConfig.Ignore,
+		projectDirs: projectDirs,
+		debounce:    debounce,
+	}
+
+	// Ctrl-C stops watching and cancels the scripts that are running
+	ctx := c.Context
+
+	executor := executor.NewWithOptions(projectConfig, projects, executor.Options{
+		WorkspaceRoot: workspaceRoot,
+	})
+	// Services restart when their project runs again, and stop with the watch
+	defer executor.StopServices()
+	verbose := c.Bool("verbose")
+
+	fmt.Printf("Running script '%s' on %d project(s)...\n\n", scriptName, len(ordered))
+	runWatchedProjects(ctx, executor, scriptName, ordered, projects, verbose)
+	w.discardRunEvents(ctx)
+	fmt.Printf("👀 Watching %d project(s) for changes (Ctrl-C to stop)...\n", len(closure.ExecutionOrder))
+
+	err = w.watch(ctx, func(changedKeys []string) error {
+		// Only the selected projects run again, not the dependencies that changed
+		affected, err := r.AffectedBy(changedKeys)
+		if err != nil {
+			return fmt.Errorf("failed to resolve affected projects: %w", err)
+		}
+		var rerun []string
+		for _, key := range affected {
+			if isTarget[key] {
+				rerun = append(rerun, key)
+			}
+		}
+
+		fmt.Printf("\n🔄 Changes in %s\n\n", strings.Join(changedKeys, ", "))
+		runWatchedProjects(ctx, executor, scriptName, rerun, projects, verbose)
+		if ctx.Err() == nil {
+			fmt.Println("👀 Watching for changes...")
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		fmt.Println("\nStopped watching.")
+	}
+	return err
+}
+
+// runSettleTime is how long the watcher must stay quiet after a run before
+// later events are taken as changes again
+const runSettleTime = 100 * time.Millisecond
+
+// projectWatcher turns the file events of watched project directories into
+// batches of changed projects
+type projectWatcher struct {
+	watcher     *fsnotify.Watcher
+	ignored     *ignore.Matcher
+	projectDirs map[string]string // Project keys by directory
+	debounce    time.Duration
+}
+
+// watch calls run with the sorted keys of the projects changed in each batch
+// of events, once no event arrived for the debounce interval, until ctx is
+// done or run fails. Events queued while run is running are discarded: they
+// come from the files the run writes itself, such as binaries and caches.
+func (w *projectWatcher) watch(ctx context.Context, run func(changedKeys []string) error) error {
+	changed := make(map[string]bool)
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Warning: file watcher: %v\n", err)
+
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if key := w.handleEvent(event); key != "" {
+				changed[key] = true
+				settled = time.After(w.debounce)
+			}
+
+		case <-settled:
+			settled = nil
+
+			var changedKeys []string
+			for key := range changed {
+				changedKeys = append(changedKeys, key)
+			}
+			sort.Strings(changedKeys)
+			changed = make(map[string]bool)
+
+			if err := run(changedKeys); err != nil {
+				return err
+			}
+			w.discardRunEvents(ctx)
+		}
+	}
+}
+
+// discardRunEvents drops the events queued during a run, until the watcher
+// has been quiet for runSettleTime. New directories are still watched.
+func (w *projectWatcher) discardRunEvents(ctx context.Context) {
+	quiet := time.NewTimer(runSettleTime)
+	defer quiet.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-quiet.C:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+			quiet.Reset(runSettleTime)
+		}
+	}
+}
+
+// handleEvent watches directories created below the watched projects and
+// returns the key of the project the event changed, or "" for ignored paths
+func (w *projectWatcher) handleEvent(event fsnotify.Event) string {
+	info, statErr := os.Stat(event.Name)
+	isDir := statErr == nil && info.IsDir()
+	if w.ignored.Match(event.Name, isDir) {
+		return ""
+	}
+	if isDir && event.Has(fsnotify.Create) {
+		if err := watchTree(w.watcher, event.Name, w.ignored); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to watch %s: %v\n", event.Name, err)
+		}
+	}
+	return owningProject(event.Name, w.projectDirs)
+}
+
+// runWatchedProjects runs script on projectKeys in order, reporting each
+// outcome. Failures do not stop the remaining projects or the watch.
+func runWatchedProjects(ctx context.Context, executor *executor.Executor, scriptName string, projectKeys []string, projects map[string]*config.AppProject, verbose bool) {
+	for i, projectKey := range projectKeys {
+		if ctx.Err() != nil {
+			return
+		}
+
+		project := projects[projectKey]
+		fmt.Printf("[%d/%d] Running on %s (%s)...", i+1, len(projectKeys), project.Config.Name, project.Config.Namespace)
+
+		result, err := executor.ExecuteScript(ctx, projectKey, scriptName)
+		if err != nil {
+			fmt.Printf(" %s\n  │ %v\n\n", colorFailure("❌ ERROR"), err)
+			continue
+		}
+		if ctx.Err() != nil {
+			fmt.Printf(" %s\n\n", colorWarning("⏹️  CANCELLED"))
+			return
+		}
+
+		if result.Success {
+			fmt.Printf(" %s (%v)\n", colorSuccess("✅ SUCCESS"), result.Duration.Truncate(time.Millisecond))
+		} else {
+			fmt.Printf(" %s (exit %d, %v)\n", colorFailure("❌ FAILED"), result.ExitCode, result.Duration.Truncate(time.Millisecond))
+		}
+
+		if verbose || !result.Success {
+			if result.Output != "" {
+				fmt.Println("Output:")
+				for _, line := range strings.Split(strings.TrimSpace(result.Output), "\n") {
+					fmt.Printf("  │ %s\n", line)
+				}
+			}
+			if result.Error != "" && !result.Success {
+				fmt.Println("Error:")
+				for _, line := range strings.Split(strings.TrimSpace(result.Error), "\n") {
+					fmt.Printf("  │ %s\n", line)
+				}
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// watchTree adds root and every directory below it that is not ignored to watcher
+func watchTree(watcher *fsnotify.Watcher, root string, ignored *ignore.Matcher) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsPermission(err) || os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != root && ignored.Match(path, true) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// owningProject returns the key of the project in projectDirs (keyed by
+// directory) with the deepest directory containing path, or "" if none does
+func owningProject(path string, projectDirs map[string]string) string {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if key, exists := projectDirs[dir]; exists {
+			return key
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchIgnoresFilesWrittenByRun(t *testing.T) {
+	projectDir := t.TempDir()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	if err := watchTree(watcher, projectDir, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &projectWatcher{
+		watcher:     watcher,
+		projectDirs: map[string]string{projectDir: "app"},
+		debounce:    20 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var runs [][]string
+	done := make(chan error)
+	go func() {
+		done <- w.watch(ctx, func(changedKeys []string) error {
+			runs = append(runs, changedKeys)
+			// Like `go build .`, the run writes a binary into the project
+			if err := os.WriteFile(filepath.Join(projectDir, "app"), []byte("binary"), 0755); err != nil {
+				t.Error(err)
+			}
+			return nil
+		})
+	}()
+
+	if err := os.WriteFile(filepath.Join(projectDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("got %d runs %v, want 1: the run's own output triggered another run", len(runs), runs)
+	}
+	if len(runs[0]) != 1 || runs[0][0] != "app" {
+		t.Errorf("changed projects = %v, want [app]", runs[0])
+	}
+}
+
+func TestWatchRunsAgainAfterRunSettled(t *testing.T) {
+	projectDir := t.TempDir()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	if err := watchTree(watcher, projectDir, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &projectWatcher{
+		watcher:     watcher,
+		projectDirs: map[string]string{projectDir: "app"},
+		debounce:    20 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ran := make(chan struct{}, 2)
+	done := make(chan error)
+	go func() {
+		done <- w.watch(ctx, func(changedKeys []string) error {
+			ran <- struct{}{}
+			return nil
+		})
+	}()
+
+	source := filepath.Join(projectDir, "main.go")
+	for i := 0; i < 2; i++ {
+		if err := os.WriteFile(source, []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatalf("edit %d did not trigger a run", i+1)
+		}
+		// Let the watcher discard the run's events before editing again
+		time.Sleep(2 * runSettleTime)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
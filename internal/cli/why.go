@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"duck/internal/resolver"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ExplainDependents lists every project affected by a change to the given
+// project, along with the dependencies through which each one is reached
+func ExplainDependents(c *cli.Context) error {
+	if !c.Bool("dependents") {
+		return fmt.Errorf("please specify a view, e.g. 'duck why --dependents <project>'")
+	}
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("please specify exactly one project")
+	}
+
+	_, projects, err := LoadProjectData()
+	if err != nil {
+		return err
+	}
+
+	projectKey, err := ResolveProjectKey(c.Args().First(), projects)
+	if err != nil {
+		return err
+	}
+
+	dependents := resolver.New(projects).GetTransitiveDependents(projectKey)
+	if len(dependents) == 0 {
+		fmt.Printf("No projects depend on %s\n", projectKey)
+		return nil
+	}
+
+	affected := map[string]bool{projectKey: true}
+	for _, dependent := range dependents {
+		affected[dependent] = true
+	}
+
+	fmt.Printf("%d project(s) depend on %s:\n", len(dependents), projectKey)
+	for _, dependent := range dependents {
+		var via []string
+		for _, dep := range projects[dependent].Config.Dependencies {
+			if affected[dep] {
+				via = append(via, dep)
+			}
+		}
+		sort.Strings(via)
+
+		fmt.Printf("  %s (via %s)\n", dependent, strings.Join(via, ", "))
+	}
+
+	return nil
+}
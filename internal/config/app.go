@@ -4,23 +4,101 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type AppConfig struct {
-	Name         string            `yaml:"name"`
-	Namespace    string            `yaml:"namespace"`
-	Description  string            `yaml:"description,omitempty"`
-	Dependencies []string          `yaml:"dependencies,omitempty"`
-	Scripts      map[string]bool   `yaml:"scripts,omitempty"`
-	Tags         []string          `yaml:"tags,omitempty"`
-	Environment  map[string]string `yaml:"environment,omitempty"`
+	Name         string                   `yaml:"name"`
+	Namespace    string                   `yaml:"namespace"`
+	Aliases      []string                 `yaml:"aliases,omitempty"` // Other names the project can be selected by
+	Description  string                   `yaml:"description,omitempty"`
+	Dependencies []string                 `yaml:"dependencies,omitempty"`
+	Scripts      map[string]ProjectScript `yaml:"scripts,omitempty"`
+	Tags         []string                 `yaml:"tags,omitempty"`
+	Environment  map[string]string        `yaml:"environment,omitempty"`
+	// ScriptSettings overrides the duck.yaml settings of individual scripts for this project
+	ScriptSettings map[string]ScriptSettings `yaml:"scriptSettings,omitempty"`
+}
+
+// ScriptSettings are per-project overrides of a script's settings. Unset fields
+// keep the value from duck.yaml.
+type ScriptSettings struct {
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	Retries *int          `yaml:"retries,omitempty"`
+}
+
+// ProjectScript is an entry of a project's scripts: true or false to enable or
+// disable a duck.yaml script, or an object overriding its command, workingDir
+// and environment for this project, which also enables it
+type ProjectScript struct {
+	Enabled     bool              `yaml:"enabled" json:"enabled"`
+	Command     string            `yaml:"command,omitempty" json:"command,omitempty"`
+	WorkingDir  string            `yaml:"workingDir,omitempty" json:"workingDir,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
+}
+
+func (s *ProjectScript) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var enabled bool
+		if err := value.Decode(&enabled); err != nil {
+			return fmt.Errorf("a script must be true, false or an object with command, workingDir and environment")
+		}
+		*s = ProjectScript{Enabled: enabled}
+		return nil
+	}
+
+	// An object enables the script unless it sets enabled: false
+	type plain ProjectScript
+	override := plain{Enabled: true}
+	if err := value.Decode(&override); err != nil {
+		return err
+	}
+	*s = ProjectScript(override)
+	return nil
+}
+
+// Apply returns script with the overrides of s applied. The environments are
+// merged, with the project's variables taking precedence.
+func (s ProjectScript) Apply(script Script) Script {
+	if s.Command != "" {
+		script.Command = s.Command
+		script.Commands = nil
+	}
+	if s.WorkingDir != "" {
+		script.WorkingDir = s.WorkingDir
+	}
+	if len(s.Environment) > 0 {
+		environment := make(map[string]string, len(script.Environment)+len(s.Environment))
+		for key, value := range script.Environment {
+			environment[key] = value
+		}
+		for key, value := range s.Environment {
+			environment[key] = value
+		}
+		script.Environment = environment
+	}
+	return script
+}
+
+// Names returns the name and the aliases the project can be selected by
+func (c *AppConfig) Names() []string {
+	return append([]string{c.Name}, c.Aliases...)
+}
+
+// ScriptEnabled reports whether the project runs the duck.yaml script name;
+// scripts it does not mention are enabled
+func (c *AppConfig) ScriptEnabled(name string) bool {
+	script, exists := c.Scripts[name]
+	return !exists || script.Enabled
 }
 
 type AppProject struct {
-	Config *AppConfig
-	Path   string
+	Config     *AppConfig
+	Path       string
+	ConfigFile string // Path of the app.yaml/project.json/project.toml the project was loaded from
 }
 
 func LoadAppConfig(path string) (*AppConfig, error) {
@@ -38,6 +116,21 @@ func LoadAppConfig(path string) (*AppConfig, error) {
 		return nil, fmt.Errorf("app name is required")
 	}
 
+	for i, alias := range config.Aliases {
+		if strings.TrimSpace(alias) == "" {
+			return nil, fmt.Errorf("aliases[%d] must not be blank", i)
+		}
+	}
+
+	for name, settings := range config.ScriptSettings {
+		if settings.Timeout < 0 {
+			return nil, fmt.Errorf("scriptSettings %s: timeout must not be negative", name)
+		}
+		if settings.Retries != nil && *settings.Retries < 0 {
+			return nil, fmt.Errorf("scriptSettings %s: retries must not be negative", name)
+		}
+	}
+
 	if config.Namespace == "" {
 		dir := filepath.Dir(path)
 		parentDir := filepath.Dir(dir)
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"duck/internal/ignore"
 )
 
 type NxProjectConfig struct {
@@ -46,7 +48,7 @@ func LoadNxProjectConfig(path string) (*AppConfig, error) {
 		Name:         nxConfig.Name,
 		Description:  fmt.Sprintf("%s project", nxConfig.ProjectType),
 		Tags:         nxConfig.Tags,
-		Scripts:      make(map[string]bool),
+		Scripts:      make(map[string]ProjectScript),
 		Dependencies: extractDependencies(nxConfig.Targets),
 		Environment:  make(map[string]string),
 	}
@@ -56,7 +58,7 @@ func LoadNxProjectConfig(path string) (*AppConfig, error) {
 	appConfig.Namespace = filepath.Base(parentDir)
 
 	for targetName := range nxConfig.Targets {
-		appConfig.Scripts[targetName] = true
+		appConfig.Scripts[targetName] = ProjectScript{Enabled: true}
 	}
 
 	return appConfig, nil
@@ -121,11 +123,11 @@ func ConvertNxTargetsToScripts(nxConfig *NxProjectConfig, projectRoot string) ma
 						cmdParts = append(cmdParts, replaceNxVariables(cmdStr, projectRoot))
 					}
 				}
-				script.Command = strings.Join(cmdParts, " && ")
+				script.Commands = cmdParts
 			}
 		}
 
-		if script.Command == "" {
+		if script.Command == "" && len(script.Commands) == 0 {
 			script.Command = fmt.Sprintf("echo 'Target %s has no command defined'", targetName)
 			if script.Description == "" {
 				script.Description = fmt.Sprintf("Nx target: %s", targetName)
@@ -138,10 +140,12 @@ func ConvertNxTargetsToScripts(nxConfig *NxProjectConfig, projectRoot string) ma
 	return scripts
 }
 
+// replaceNxVariables maps the Nx variables in command to the duck variables
+// the executor expands when the script runs
 func replaceNxVariables(command string, projectRoot string) string {
 	replacements := map[string]string{
 		"{projectRoot}":   "{projectRoot}",
-		"{workspaceRoot}": ".",
+		"{workspaceRoot}": "{workspaceRoot}",
 		"{projectName}":   "{projectName}",
 	}
 
@@ -153,7 +157,7 @@ func replaceNxVariables(command string, projectRoot string) string {
 	return result
 }
 
-func ScanNxTargets(targetDirectory string) (map[string]Script, error) {
+func ScanNxTargets(targetDirectory string, ignored *ignore.Matcher) (map[string]Script, error) {
 	scriptsMap := make(map[string]Script)
 	targetNames := make(map[string]bool)
 
@@ -165,6 +169,10 @@ func ScanNxTargets(targetDirectory string) (map[string]Script, error) {
 			return err
 		}
 
+		if info.IsDir() && path != targetDirectory && ignored.Match(path, true) {
+			return filepath.SkipDir
+		}
+
 		if info.Name() == "project.json" {
 			data, err := os.ReadFile(path)
 			if err != nil {
@@ -197,7 +205,7 @@ func ScanNxTargets(targetDirectory string) (map[string]Script, error) {
 									cmdParts = append(cmdParts, replaceNxVariables(cmdStr, projectDir))
 								}
 							}
-							script.Command = strings.Join(cmdParts, " && ")
+							script.Commands = cmdParts
 						}
 					}
 
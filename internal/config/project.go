@@ -4,6 +4,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"duck/internal/ignore"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,24 +20,190 @@ type ProjectConfigFormat string
 const (
 	FormatDuck ProjectConfigFormat = "duck"
 	FormatNx   ProjectConfigFormat = "nx"
+	FormatToml ProjectConfigFormat = "toml"
 	FormatAll  ProjectConfigFormat = "all"
+	// FormatAllToml is FormatAll plus project.toml files
+	FormatAllToml ProjectConfigFormat = "all+toml"
 )
 
+// ValidFormats lists the accepted values of projectConfigFormat
+var ValidFormats = []ProjectConfigFormat{FormatDuck, FormatNx, FormatToml, FormatAll, FormatAllToml}
+
+// IsValid reports whether f is one of ValidFormats
+func (f ProjectConfigFormat) IsValid() bool {
+	return slices.Contains(ValidFormats, f)
+}
+
+// ConfigFileNames returns the project config files scanned for in this format,
+// ordered by precedence when several exist in the same directory
+func (f ProjectConfigFormat) ConfigFileNames() []string {
+	switch f {
+	case FormatDuck:
+		return []string{"app.yaml"}
+	case FormatNx:
+		return []string{"project.json"}
+	case FormatToml:
+		return []string{"project.toml"}
+	case FormatAll:
+		return []string{"app.yaml", "project.json"}
+	case FormatAllToml:
+		return []string{"app.yaml", "project.toml", "project.json"}
+	}
+	return nil
+}
+
+// ConfigFileNames returns the project config files scanned for, ordered by
+// precedence: those of the format, with ProjectConfigFilenames in place of app.yaml
+func (c *ProjectConfig) ConfigFileNames() []string {
+	var names []string
+	for _, name := range c.ProjectConfigFormat.ConfigFileNames() {
+		if name == "app.yaml" {
+			names = append(names, c.AppConfigFileNames()...)
+		} else {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// AppConfigFileNames returns the names of duck-format project config files
+func (c *ProjectConfig) AppConfigFileNames() []string {
+	if len(c.ProjectConfigFilenames) > 0 {
+		return c.ProjectConfigFilenames
+	}
+	return []string{"app.yaml"}
+}
+
+// formatList renders ValidFormats for error messages, e.g. 'duck', 'nx', or 'all'
+func formatList() string {
+	quoted := make([]string, len(ValidFormats))
+	for i, format := range ValidFormats {
+		quoted[i] = fmt.Sprintf("'%s'", format)
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + ", or " + quoted[len(quoted)-1]
+}
+
+// InvalidFormatError describes a format that is not one of ValidFormats
+func InvalidFormatError(format string) error {
+	return fmt.Errorf("must be %s, got '%s'", formatList(), format)
+}
+
 type ProjectConfig struct {
 	TargetDirectory       string              `yaml:"targetDirectory"`
 	AdditionalDirectories []string            `yaml:"additionalDirectories,omitempty"`
 	ProjectConfigFormat   ProjectConfigFormat `yaml:"projectConfigFormat"`
 	Scripts               map[string]Script   `yaml:"scripts"`
+
+	// ProjectConfigFilenames replaces app.yaml as the names of duck-format
+	// project config files, e.g. ["service.yaml"]. When a directory has several,
+	// the first listed wins.
+	ProjectConfigFilenames []string `yaml:"projectConfigFilenames,omitempty"`
+
+	// Shell runs script commands, e.g. "bash -c"; the command is appended as the
+	// last argument. Defaults to "sh -c" on Unix and "cmd /c" on Windows.
+	Shell string `yaml:"shell,omitempty"`
+
+	// PreScan is a command run in the workspace root before projects are
+	// scanned, e.g. to generate project config files
+	PreScan string `yaml:"preScan,omitempty"`
+
+	// Environment is set for every script, below script and project environment
+	Environment map[string]string `yaml:"environment,omitempty"`
+
+	// ScanCache enables the on-disk cache of parsed project configs in .duck/cache.json
+	ScanCache bool `yaml:"scanCache,omitempty"`
+
+	// Ignore holds the .duckignore rules of the workspace containing this config
+	Ignore *ignore.Matcher `yaml:"-"`
+	// DefaultTargetDirectory is set when duck.yaml has no targetDirectory and
+	// the workspace root is scanned instead
+	DefaultTargetDirectory bool `yaml:"-"`
+}
+
+// LoadOptions tunes how LoadProjectConfigWithOptions loads a workspace
+type LoadOptions struct {
+	// NoDefaultIgnores disables the built-in ignore patterns (.git, node_modules, ...)
+	NoDefaultIgnores bool
 }
 
 type Script struct {
-	Command     string            `yaml:"command"`
+	Command string `yaml:"command"`
+	// Commands are run one after another, stopping at the first that fails.
+	// When set, Command is ignored.
+	Commands    []string          `yaml:"commands,omitempty"`
 	Description string            `yaml:"description"`
 	WorkingDir  string            `yaml:"workingDir,omitempty"`
 	Environment map[string]string `yaml:"environment,omitempty"`
+	// Retries is the number of additional attempts made after a failed run
+	Retries int `yaml:"retries,omitempty"`
+	// RetryOn limits retries to the listed exit codes; when empty any failure is retried
+	RetryOn []int `yaml:"retryOn,omitempty"`
+	// Timeout stops an attempt that runs longer than this; zero means no limit
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Shell overrides the workspace shell for this script
+	Shell string `yaml:"shell,omitempty"`
+	// IsolateEnv starts the script from an empty environment, keeping only the
+	// allowlisted variables, instead of inheriting duck's environment
+	IsolateEnv bool `yaml:"isolateEnv,omitempty"`
+	// ReadyWhen makes the script a background service: a regular expression
+	// matched against each line of its output, after which the run moves on
+	// and leaves the script running. Retries do not apply, and timeout limits
+	// how long it may take to become ready.
+	ReadyWhen string `yaml:"readyWhen,omitempty"`
+	// Extends names a script to inherit command(s), environment, workingDir,
+	// timeout and shell from; fields set on this script override the inherited ones
+	Extends string `yaml:"extends,omitempty"`
+}
+
+// Steps returns the commands script runs in order: Commands, or else Command
+func (s Script) Steps() []string {
+	if len(s.Commands) > 0 {
+		return s.Commands
+	}
+	return []string{s.Command}
+}
+
+// ShouldRetry reports whether a run that exited with exitCode is eligible for a retry
+func (s Script) ShouldRetry(exitCode int) bool {
+	if len(s.RetryOn) == 0 {
+		return true
+	}
+
+	for _, code := range s.RetryOn {
+		if code == exitCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WorkspaceConfigFile is the name of the workspace configuration file
+const WorkspaceConfigFile = "duck.yaml"
+
+// FindWorkspaceRoot returns the nearest directory, starting from the current
+// one and walking up its parents, that contains duck.yaml
+func FindWorkspaceRoot() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if info, err := os.Stat(filepath.Join(dir, WorkspaceConfigFile)); err == nil && !info.IsDir() {
+			return dir, nil
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return "", fmt.Errorf("no %s found in %s or any parent directory", WorkspaceConfigFile, cwd)
+		}
+	}
 }
 
 func LoadProjectConfig(path string) (*ProjectConfig, error) {
+	return LoadProjectConfigWithOptions(path, LoadOptions{})
+}
+
+func LoadProjectConfigWithOptions(path string, options LoadOptions) (*ProjectConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read project config: %w", err)
@@ -45,14 +218,34 @@ func LoadProjectConfig(path string) (*ProjectConfig, error) {
 		// Default to current directory if not specified
 		// Users must explicitly configure targetDirectory in duck.yaml for non-standard layouts
 		config.TargetDirectory = "."
+		config.DefaultTargetDirectory = true
+	}
+
+	if config.Shell != "" && len(strings.Fields(config.Shell)) == 0 {
+		return nil, fmt.Errorf("shell must not be blank")
+	}
+
+	seenFilenames := make(map[string]bool)
+	for _, name := range config.ProjectConfigFilenames {
+		switch {
+		case strings.TrimSpace(name) == "":
+			return nil, fmt.Errorf("projectConfigFilenames: file names must not be empty")
+		case strings.ContainsAny(name, `/\`):
+			return nil, fmt.Errorf("projectConfigFilenames: %s must be a file name, not a path", name)
+		case name == "project.json" || name == "project.toml":
+			return nil, fmt.Errorf("projectConfigFilenames: %s is scanned by the nx and toml formats", name)
+		case seenFilenames[name]:
+			return nil, fmt.Errorf("projectConfigFilenames: %s is listed twice", name)
+		}
+		seenFilenames[name] = true
 	}
 
 	if config.ProjectConfigFormat == "" {
 		config.ProjectConfigFormat = FormatDuck
 	}
 
-	if config.ProjectConfigFormat != FormatDuck && config.ProjectConfigFormat != FormatNx && config.ProjectConfigFormat != FormatAll {
-		return nil, fmt.Errorf("invalid projectConfigFormat: must be 'duck', 'nx', or 'all', got '%s'", config.ProjectConfigFormat)
+	if !config.ProjectConfigFormat.IsValid() {
+		return nil, fmt.Errorf("invalid projectConfigFormat: %w", InvalidFormatError(string(config.ProjectConfigFormat)))
 	}
 
 	if !filepath.IsAbs(config.TargetDirectory) {
@@ -74,8 +267,17 @@ func LoadProjectConfig(path string) (*ProjectConfig, error) {
 		}
 	}
 
-	if config.ProjectConfigFormat == FormatNx || config.ProjectConfigFormat == FormatAll {
-		nxScripts, err := ScanNxTargets(config.TargetDirectory)
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for project config: %w", err)
+	}
+	config.Ignore, err = ignore.Load(filepath.Dir(absPath), !options.NoDefaultIgnores)
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(config.ProjectConfigFormat.ConfigFileNames(), "project.json") {
+		nxScripts, err := ScanNxTargets(config.TargetDirectory, config.Ignore)
 		if err != nil {
 			fmt.Printf("Warning: Failed to scan Nx targets: %v\n", err)
 		} else {
@@ -91,5 +293,131 @@ func LoadProjectConfig(path string) (*ProjectConfig, error) {
 		}
 	}
 
+	// Inheritance is resolved once the Nx targets are merged, so that a
+	// script can extend one of them
+	if err := resolveScriptInheritance(config.Scripts); err != nil {
+		return nil, err
+	}
+
+	for name, script := range config.Scripts {
+		if err := script.Validate(); err != nil {
+			return nil, fmt.Errorf("script %s: %w", name, err)
+		}
+	}
+
 	return &config, nil
 }
+
+// Validate checks the settings of script. Projects can override a script's
+// command, so the script a project runs is validated again once its override
+// is applied.
+func (s Script) Validate() error {
+	if s.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if s.Shell != "" && len(strings.Fields(s.Shell)) == 0 {
+		return fmt.Errorf("shell must not be blank")
+	}
+	if len(s.Commands) == 0 && s.Command != "" && strings.TrimSpace(s.Command) == "" {
+		return fmt.Errorf("command must not be blank")
+	}
+	for i, command := range s.Commands {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("commands[%d] must not be blank", i)
+		}
+	}
+	if s.ReadyWhen != "" {
+		if len(s.Commands) > 1 {
+			return fmt.Errorf("readyWhen needs a single command, got %d commands", len(s.Commands))
+		}
+		if _, err := regexp.Compile(s.ReadyWhen); err != nil {
+			return fmt.Errorf("invalid readyWhen: %w", err)
+		}
+	}
+	return nil
+}
+
+// resolveScriptInheritance replaces every script that extends another with the
+// merged result of its inheritance chain
+func resolveScriptInheritance(scripts map[string]Script) error {
+	resolved := make(map[string]bool)
+
+	var resolve func(name string, chain []string) error
+	resolve = func(name string, chain []string) error {
+		if resolved[name] {
+			return nil
+		}
+
+		for i, seen := range chain {
+			if seen == name {
+				cycle := append(chain[i:], name)
+				return fmt.Errorf("script %s: extends cycle: %s", name, strings.Join(cycle, " -> "))
+			}
+		}
+
+		script := scripts[name]
+		if script.Extends == "" {
+			resolved[name] = true
+			return nil
+		}
+
+		if _, exists := scripts[script.Extends]; !exists {
+			return fmt.Errorf("script %s: extends unknown script %s", name, script.Extends)
+		}
+		if err := resolve(script.Extends, append(chain, name)); err != nil {
+			return err
+		}
+
+		scripts[name] = script.inherit(scripts[script.Extends])
+		resolved[name] = true
+		return nil
+	}
+
+	names := make([]string, 0, len(scripts))
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := resolve(name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inherit fills the fields of s that are unset from parent. Environment
+// variables are merged, with the ones of s taking precedence.
+func (s Script) inherit(parent Script) Script {
+	if s.Command == "" && len(s.Commands) == 0 {
+		s.Command = parent.Command
+		s.Commands = parent.Commands
+	}
+	if s.WorkingDir == "" {
+		s.WorkingDir = parent.WorkingDir
+	}
+	if s.Timeout == 0 {
+		s.Timeout = parent.Timeout
+	}
+	if s.Shell == "" {
+		s.Shell = parent.Shell
+	}
+
+	if len(parent.Environment) > 0 {
+		environment := make(map[string]string, len(parent.Environment)+len(s.Environment))
+		for key, value := range parent.Environment {
+			environment[key] = value
+		}
+		for key, value := range s.Environment {
+			environment[key] = value
+		}
+		s.Environment = environment
+	}
+
+	return s
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeNxWorkspace writes duck.yaml with the given scripts section and an Nx
+// project.json with a build target, and returns the path of duck.yaml
+func writeNxWorkspace(t *testing.T, scripts string) string {
+	t.Helper()
+	root := t.TempDir()
+
+	projectDir := filepath.Join(root, "apps", "web")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	projectJSON := `{"name": "web", "targets": {"build": {"executor": "nx:run-commands", "options": {"command": "vite build"}}}}`
+	if err := os.WriteFile(filepath.Join(projectDir, "project.json"), []byte(projectJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	duckYAML := fmt.Sprintf("targetDirectory: %s\nprojectConfigFormat: nx\nscripts:\n%s", root, scripts)
+	path := filepath.Join(root, WorkspaceConfigFile)
+	if err := os.WriteFile(path, []byte(duckYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestScriptExtendsNxTarget(t *testing.T) {
+	path := writeNxWorkspace(t, `  build-ci:
+    extends: build
+    environment:
+      CI: "1"
+`)
+
+	config, err := LoadProjectConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, exists := config.Scripts["build-ci"]
+	if !exists {
+		t.Fatal("script build-ci not loaded")
+	}
+	if script.Command != "vite build" {
+		t.Errorf("Command = %q, want the Nx target's %q", script.Command, "vite build")
+	}
+	if script.WorkingDir != "{projectRoot}" {
+		t.Errorf("WorkingDir = %q, want the Nx target's {projectRoot}", script.WorkingDir)
+	}
+	if script.Environment["CI"] != "1" {
+		t.Errorf("Environment = %v, want CI=1 from build-ci", script.Environment)
+	}
+}
+
+func TestScriptExtendsUnknownScript(t *testing.T) {
+	path := writeNxWorkspace(t, `  build-ci:
+    extends: compile
+`)
+
+	_, err := LoadProjectConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "script build-ci: extends unknown script compile") {
+		t.Fatalf("err = %v, want an unknown script error", err)
+	}
+}
+
+// loadProjectFile writes content to apps/web/name in a temporary directory and
+// loads it with load
+func loadProjectFile(t *testing.T, name, content string, load func(string) (*AppConfig, error)) (*AppConfig, error) {
+	t.Helper()
+	projectDir := filepath.Join(t.TempDir(), "apps", "web")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(projectDir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return load(path)
+}
+
+func TestLoadTomlProjectConfigMatchesAppYaml(t *testing.T) {
+	appYAML := `name: web
+aliases: [frontend]
+dependencies: [api]
+tags: [js]
+environment:
+  NODE_ENV: production
+scripts:
+  lint: false
+  test: true
+  build:
+    command: vite build
+    workingDir: "{projectRoot}/src"
+    environment:
+      CI: "1"
+  deploy:
+    enabled: false
+    command: ./deploy.sh
+scriptSettings:
+  build:
+    timeout: 5m
+    retries: 2
+  test:
+    timeout: 90s
+`
+	projectTOML := `name = "web"
+aliases = ["frontend"]
+dependencies = ["api"]
+tags = ["js"]
+
+[environment]
+NODE_ENV = "production"
+
+[scripts]
+lint = false
+test = true
+
+[scripts.build]
+command = "vite build"
+workingDir = "{projectRoot}/src"
+environment = { CI = "1" }
+
+[scripts.deploy]
+enabled = false
+command = "./deploy.sh"
+
+[scriptSettings.build]
+timeout = "5m"
+retries = 2
+
+[scriptSettings.test]
+timeout = "90s"
+`
+
+	want, err := loadProjectFile(t, "app.yaml", appYAML, LoadAppConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadProjectFile(t, "project.toml", projectTOML, LoadTomlProjectConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Namespace != "apps" {
+		t.Errorf("Namespace = %q, want the parent directory apps", got.Namespace)
+	}
+	if script := got.Scripts["lint"]; script.Enabled {
+		t.Error("scripts.lint = false loaded as enabled")
+	}
+	if script := got.Scripts["build"]; !script.Enabled || script.Command != "vite build" || script.Environment["CI"] != "1" {
+		t.Errorf("scripts.build = %+v, want an enabled override", script)
+	}
+	if script := got.Scripts["deploy"]; script.Enabled {
+		t.Error("scripts.deploy with enabled = false loaded as enabled")
+	}
+	if settings := got.ScriptSettings["build"]; settings.Timeout != 5*time.Minute || settings.Retries == nil || *settings.Retries != 2 {
+		t.Errorf("scriptSettings.build = %+v, want a 5m timeout and 2 retries", settings)
+	}
+	if settings := got.ScriptSettings["test"]; settings.Timeout != 90*time.Second || settings.Retries != nil {
+		t.Errorf("scriptSettings.test = %+v, want a 90s timeout", settings)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("project.toml loaded as\n%+v\nwant the same as app.yaml\n%+v", got, want)
+	}
+}
+
+func TestLoadTomlProjectConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"missing name", `namespace = "core"`, "project name is required"},
+		{"blank alias", "name = \"web\"\naliases = [\" \"]", "aliases[0] must not be blank"},
+		{"script of the wrong type", "name = \"web\"\n[scripts]\nbuild = \"vite build\"", "scripts build: a script must be true, false or a table"},
+		{"negative retries", "name = \"web\"\n[scriptSettings.build]\nretries = -1", "scriptSettings build: retries must not be negative"},
+		{"invalid duration", "name = \"web\"\n[scriptSettings.build]\ntimeout = \"soon\"", "failed to parse toml project config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadProjectFile(t, "project.toml", tt.content, LoadTomlProjectConfig)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		script Script
+		want   string
+	}{
+		{"valid", Script{Command: "go test ./...", ReadyWhen: "^ok"}, ""},
+		{"negative retries", Script{Command: "true", Retries: -1}, "retries must not be negative"},
+		{"blank shell", Script{Command: "true", Shell: " "}, "shell must not be blank"},
+		{"blank command", Script{Command: "  "}, "command must not be blank"},
+		{"blank step", Script{Commands: []string{"true", " "}}, "commands[1] must not be blank"},
+		{"service with steps", Script{Commands: []string{"make", "./server"}, ReadyWhen: "^ok"}, "readyWhen needs a single command, got 2 commands"},
+		{"invalid readyWhen", Script{Command: "./server", ReadyWhen: "("}, "invalid readyWhen"},
+		// A project's override replaces the steps with its command
+		{"overridden service", ProjectScript{Command: "./server"}.Apply(Script{Commands: []string{"make", "./server"}, ReadyWhen: "^ok"}), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.script.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("err = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
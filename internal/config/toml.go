@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TomlProjectConfig is a project.toml file. It has the same fields as app.yaml.
+type TomlProjectConfig struct {
+	Name           string                        `toml:"name"`
+	Namespace      string                        `toml:"namespace"`
+	Aliases        []string                      `toml:"aliases"`
+	Description    string                        `toml:"description"`
+	Dependencies   []string                      `toml:"dependencies"`
+	Scripts        map[string]toml.Primitive     `toml:"scripts"`
+	Tags           []string                      `toml:"tags"`
+	Environment    map[string]string             `toml:"environment"`
+	ScriptSettings map[string]TomlScriptSettings `toml:"scriptSettings"`
+}
+
+// TomlProjectScript is the object form of a project.toml script, which
+// overrides the duck.yaml script for the project
+type TomlProjectScript struct {
+	Enabled     *bool             `toml:"enabled"`
+	Command     string            `toml:"command"`
+	WorkingDir  string            `toml:"workingDir"`
+	Environment map[string]string `toml:"environment"`
+}
+
+// TomlScriptSettings are the scriptSettings of a project.toml file; timeouts
+// are duration strings such as "5m"
+type TomlScriptSettings struct {
+	Timeout time.Duration `toml:"timeout"`
+	Retries *int          `toml:"retries"`
+}
+
+func LoadTomlProjectConfig(path string) (*AppConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read toml project config: %w", err)
+	}
+
+	var tomlConfig TomlProjectConfig
+	metadata, err := toml.Decode(string(data), &tomlConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse toml project config: %w", err)
+	}
+
+	if tomlConfig.Name == "" {
+		return nil, fmt.Errorf("project name is required")
+	}
+
+	for i, alias := range tomlConfig.Aliases {
+		if strings.TrimSpace(alias) == "" {
+			return nil, fmt.Errorf("aliases[%d] must not be blank", i)
+		}
+	}
+
+	appConfig := &AppConfig{
+		Name:         tomlConfig.Name,
+		Namespace:    tomlConfig.Namespace,
+		Aliases:      tomlConfig.Aliases,
+		Description:  tomlConfig.Description,
+		Dependencies: tomlConfig.Dependencies,
+		Tags:         tomlConfig.Tags,
+		Environment:  tomlConfig.Environment,
+	}
+
+	// Scripts are true, false, or a table overriding the script
+	for name, primitive := range tomlConfig.Scripts {
+		if appConfig.Scripts == nil {
+			appConfig.Scripts = make(map[string]ProjectScript)
+		}
+
+		var enabled bool
+		if err := metadata.PrimitiveDecode(primitive, &enabled); err == nil {
+			appConfig.Scripts[name] = ProjectScript{Enabled: enabled}
+			continue
+		}
+
+		var override TomlProjectScript
+		if err := metadata.PrimitiveDecode(primitive, &override); err != nil {
+			return nil, fmt.Errorf("scripts %s: a script must be true, false or a table with command, workingDir and environment", name)
+		}
+		appConfig.Scripts[name] = ProjectScript{
+			Enabled:     override.Enabled == nil || *override.Enabled,
+			Command:     override.Command,
+			WorkingDir:  override.WorkingDir,
+			Environment: override.Environment,
+		}
+	}
+
+	for name, settings := range tomlConfig.ScriptSettings {
+		if settings.Timeout < 0 {
+			return nil, fmt.Errorf("scriptSettings %s: timeout must not be negative", name)
+		}
+		if settings.Retries != nil && *settings.Retries < 0 {
+			return nil, fmt.Errorf("scriptSettings %s: retries must not be negative", name)
+		}
+
+		if appConfig.ScriptSettings == nil {
+			appConfig.ScriptSettings = make(map[string]ScriptSettings)
+		}
+		appConfig.ScriptSettings[name] = ScriptSettings{Timeout: settings.Timeout, Retries: settings.Retries}
+	}
+
+	if appConfig.Namespace == "" {
+		dir := filepath.Dir(path)
+		parentDir := filepath.Dir(dir)
+		appConfig.Namespace = filepath.Base(parentDir)
+	}
+
+	return appConfig, nil
+}
@@ -3,15 +3,122 @@ package goscan
 import (
 	"duck/internal/dependencyscanner"
 	"fmt"
+	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
 // AnalyzeProjectDependencies performs a deep analysis of Go project dependencies
 // It combines go.mod parsing with actual import usage
 func AnalyzeProjectDependencies(projectPath string) (*dependencyscanner.ProjectDependencies, error) {
-	scanner := NewGoScanner()
+	return analyzeProject(NewGoScanner(), projectPath)
+}
+
+// ImportAnalyzer is a dependencyscanner.Scanner that reports the dependencies
+// of AnalyzeProjectDependencies: go.mod requirements enriched with the import
+// paths actually used
+type ImportAnalyzer struct {
+	*GoScanner
+	localPackages []string
+}
+
+// NewImportAnalyzer returns an ImportAnalyzer using scanner's settings
+func NewImportAnalyzer(scanner *GoScanner) *ImportAnalyzer {
+	return &ImportAnalyzer{GoScanner: scanner}
+}
+
+// SetLocalPackages sets the import paths of the workspace's projects: their
+// module paths, or their package paths when they share a module. A project
+// importing one of them depends on it directly, even when no go.mod requires
+// it, as in monorepos with a single module.
+func (a *ImportAnalyzer) SetLocalPackages(paths []string) {
+	a.localPackages = paths
+}
+
+// CanScan accepts projects with a go.mod and, once local packages are set,
+// projects with Go files inside a module of a parent directory
+func (a *ImportAnalyzer) CanScan(projectPath string) bool {
+	if a.GoScanner.CanScan(projectPath) {
+		return true
+	}
+	if len(a.localPackages) == 0 {
+		return false
+	}
+	if _, err := PackagePath(projectPath); err != nil {
+		return false
+	}
+	return a.containsGoFiles(projectPath)
+}
+
+// ScanProject scans go.mod and the imports of the project at projectPath. A
+// project without a go.mod of its own only has the local packages it imports.
+func (a *ImportAnalyzer) ScanProject(projectPath string) (*dependencyscanner.ProjectDependencies, error) {
+	deps := &dependencyscanner.ProjectDependencies{
+		ProjectPath:  projectPath,
+		Language:     "go",
+		Dependencies: make([]dependencyscanner.Dependency, 0),
+	}
+	if a.GoScanner.CanScan(projectPath) {
+		var err error
+		if deps, err = a.GoScanner.ScanProject(projectPath); err != nil {
+			return nil, err
+		}
+	}
+
+	imports, err := a.ScanImports(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan imports: %w", err)
+	}
+	enrichImportPaths(deps, imports)
+	a.addLocalImports(deps, projectPath, imports)
+
+	return deps, nil
+}
+
+// addLocalImports adds a direct dependency on each local package that
+// projectPath imports and go.mod does not already require. An import belongs
+// to the local package with the longest matching path, so that packages nested
+// in another project's directory are told apart.
+func (a *ImportAnalyzer) addLocalImports(deps *dependencyscanner.ProjectDependencies, projectPath string, imports []string) {
+	own, _ := PackagePath(projectPath)
 
+	required := make(map[string]bool, len(deps.Dependencies))
+	for _, dep := range deps.Dependencies {
+		required[dep.Target] = true
+	}
+
+	importPaths := make(map[string][]string)
+	for _, imp := range imports {
+		target := ""
+		for _, local := range a.localPackages {
+			if (imp == local || strings.HasPrefix(imp, local+"/")) && len(local) > len(target) {
+				target = local
+			}
+		}
+		if target != "" && target != own && !required[target] {
+			importPaths[target] = append(importPaths[target], imp)
+		}
+	}
+
+	targets := make([]string, 0, len(importPaths))
+	for target := range importPaths {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	for _, target := range targets {
+		sort.Strings(importPaths[target])
+		deps.Dependencies = append(deps.Dependencies, dependencyscanner.Dependency{
+			Target:      target,
+			IsDirect:    true,
+			ImportPaths: importPaths[target],
+		})
+	}
+}
+
+// analyzeProject is AnalyzeProjectDependencies using the given scanner's settings
+func analyzeProject(scanner *GoScanner, projectPath string) (*dependencyscanner.ProjectDependencies, error) {
 	// First, get dependencies from go.mod
 	deps, err := scanner.ScanProject(projectPath)
 	if err != nil {
@@ -23,7 +130,14 @@ func AnalyzeProjectDependencies(projectPath string) (*dependencyscanner.ProjectD
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan imports: %w", err)
 	}
+	enrichImportPaths(deps, imports)
+
+	return deps, nil
+}
 
+// enrichImportPaths sets the import paths of the dependencies of deps to those
+// of imports under them
+func enrichImportPaths(deps *dependencyscanner.ProjectDependencies, imports []string) {
 	// Create a map of used imports
 	usedImports := make(map[string][]string)
 	for _, imp := range imports {
@@ -39,8 +153,6 @@ func AnalyzeProjectDependencies(projectPath string) (*dependencyscanner.ProjectD
 			dep.ImportPaths = paths
 		}
 	}
-
-	return deps, nil
 }
 
 // extractBasePackage extracts the base package name from an import path
@@ -59,3 +171,98 @@ func extractBasePackage(importPath string) string {
 
 	return importPath
 }
+
+// ModuleGraph runs `go mod graph` in projectPath and returns the requirement
+// edges keyed by module path (versions stripped). The main module is returned
+// separately so callers can tell direct requirements apart.
+func ModuleGraph(projectPath string) (string, map[string][]string, error) {
+	cmd := exec.Command("go", "mod", "graph")
+	cmd.Dir = projectPath
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("go mod graph failed: %w", err)
+	}
+
+	mainModule := ""
+	edges := make(map[string][]string)
+
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		from := stripModuleVersion(fields[0])
+		to := stripModuleVersion(fields[1])
+
+		// Only the main module is listed without a version
+		if mainModule == "" && !strings.Contains(fields[0], "@") {
+			mainModule = from
+		}
+
+		edges[from] = append(edges[from], to)
+	}
+
+	return mainModule, edges, nil
+}
+
+// AnnotateIndirectVia fills Dependency.Via for the indirect dependencies of a
+// project with the direct dependencies that pull them in, using `go mod graph`.
+// Dependencies are left untouched when the graph is unavailable.
+func AnnotateIndirectVia(deps *dependencyscanner.ProjectDependencies, projectPath string) error {
+	mainModule, edges, err := ModuleGraph(projectPath)
+	if err != nil {
+		return err
+	}
+
+	// For each direct requirement, find every module reachable from it
+	via := make(map[string]map[string]bool)
+	for _, direct := range edges[mainModule] {
+		visited := map[string]bool{direct: true}
+		queue := []string{direct}
+
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+
+			for _, next := range edges[current] {
+				if visited[next] {
+					continue
+				}
+				visited[next] = true
+				queue = append(queue, next)
+
+				if via[next] == nil {
+					via[next] = make(map[string]bool)
+				}
+				via[next][direct] = true
+			}
+		}
+	}
+
+	for i := range deps.Dependencies {
+		dep := &deps.Dependencies[i]
+		if dep.IsDirect {
+			continue
+		}
+
+		dep.Via = nil
+		for direct := range via[dep.Target] {
+			if direct != dep.Target {
+				dep.Via = append(dep.Via, direct)
+			}
+		}
+		sort.Strings(dep.Via)
+	}
+
+	return nil
+}
+
+// stripModuleVersion turns "module@version" into "module"
+func stripModuleVersion(module string) string {
+	if index := strings.LastIndex(module, "@"); index != -1 {
+		return module[:index]
+	}
+	return module
+}
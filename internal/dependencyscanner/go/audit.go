@@ -0,0 +1,93 @@
+package goscan
+
+import (
+	"duck/internal/dependencyscanner"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ModuleAudit cross-references the requirements of a project's go.mod with the
+// imports of its Go files
+type ModuleAudit struct {
+	ProjectPath string
+	Module      string
+	// Unused are the direct requirements no import falls under, which
+	// `go mod tidy` would remove
+	Unused []dependencyscanner.Dependency
+	// Undeclared maps the imported modules go.mod does not require to their
+	// import paths. The module is guessed from the import path.
+	Undeclared map[string][]string
+}
+
+// AuditModule compares the go.mod requirements of the project at projectPath
+// with its imports. Test files are not scanned, so requirements used only by
+// tests are reported as unused.
+func (gs *GoScanner) AuditModule(projectPath string) (*ModuleAudit, error) {
+	deps, err := gs.ScanProject(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	modulePath, err := ReadModulePath(filepath.Join(projectPath, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	imports, err := gs.ScanImports(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan imports: %w", err)
+	}
+
+	audit := &ModuleAudit{
+		ProjectPath: projectPath,
+		Module:      modulePath,
+		Undeclared:  make(map[string][]string),
+	}
+
+	used := make(map[string]bool)
+	for _, imp := range imports {
+		if underModule(imp, modulePath) {
+			continue
+		}
+
+		// The longest requirement wins, as nested modules are separate modules
+		required := ""
+		for _, dep := range deps.Dependencies {
+			if underModule(imp, dep.Target) && len(dep.Target) > len(required) {
+				required = dep.Target
+			}
+		}
+		if required != "" {
+			used[required] = true
+			continue
+		}
+
+		// Paths without a dot in their first element belong to the standard library
+		if !strings.Contains(strings.SplitN(imp, "/", 2)[0], ".") {
+			continue
+		}
+		module := extractBasePackage(imp)
+		audit.Undeclared[module] = append(audit.Undeclared[module], imp)
+	}
+
+	for _, dep := range deps.Dependencies {
+		if dep.IsDirect && !used[dep.Target] {
+			audit.Unused = append(audit.Unused, dep)
+		}
+	}
+	sort.Slice(audit.Unused, func(i, j int) bool {
+		return audit.Unused[i].Target < audit.Unused[j].Target
+	})
+	for module := range audit.Undeclared {
+		sort.Strings(audit.Undeclared[module])
+	}
+
+	return audit, nil
+}
+
+// underModule reports whether importPath is a package of the module modulePath
+func underModule(importPath, modulePath string) bool {
+	return importPath == modulePath || strings.HasPrefix(importPath, modulePath+"/")
+}
@@ -2,50 +2,150 @@ package goscan
 
 import (
 	"duck/internal/dependencyscanner"
+	"duck/internal/ignore"
 	"fmt"
 	"path/filepath"
+	"runtime"
+	"sync"
 )
 
 // GraphBuilder builds a dependency graph for multiple Go projects
 type GraphBuilder struct {
-	scanner  *GoScanner
-	registry *dependencyscanner.ScannerRegistry
+	scanner       *GoScanner
+	analyzer      *ImportAnalyzer
+	registry      *dependencyscanner.ScannerRegistry
+	languages     []string // Languages of the registered scanners, in order
+	workers       int
+	cacheReadOnly bool
 }
 
-// NewGraphBuilder creates a new graph builder
+// NewGraphBuilder creates a new graph builder. Go projects are analyzed like
+// AnalyzeProjectDependencies does: go.mod requirements enriched with the
+// import paths actually used.
 func NewGraphBuilder() *GraphBuilder {
 	scanner := NewGoScanner()
+	analyzer := NewImportAnalyzer(scanner)
 	registry := dependencyscanner.NewScannerRegistry()
-	registry.RegisterScanner(scanner)
+	registry.RegisterScanner(analyzer)
 
 	return &GraphBuilder{
-		scanner:  scanner,
-		registry: registry,
+		scanner:   scanner,
+		analyzer:  analyzer,
+		registry:  registry,
+		languages: []string{analyzer.GetLanguage()},
+		workers:   runtime.NumCPU(),
 	}
 }
 
-// BuildGraph scans all projects in the workspace and builds a dependency graph
+// RegisterScanner adds the scanner of another language. Each project is
+// analyzed by the first registered scanner that can scan it, Go first.
+func (gb *GraphBuilder) RegisterScanner(scanner dependencyscanner.Scanner) {
+	gb.registry.RegisterScanner(scanner)
+	gb.languages = append(gb.languages, scanner.GetLanguage())
+}
+
+// SetIgnore sets the rules for directories skipped while scanning imports
+func (gb *GraphBuilder) SetIgnore(matcher *ignore.Matcher) {
+	gb.scanner.SetIgnore(matcher)
+}
+
+// SetLocalPackages sets the import paths of the workspace's projects, see
+// ImportAnalyzer.SetLocalPackages
+func (gb *GraphBuilder) SetLocalPackages(paths []string) {
+	gb.analyzer.SetLocalPackages(paths)
+}
+
+// SetCacheReadOnly makes BuildGraphCached use the graph cache without writing
+// it back
+func (gb *GraphBuilder) SetCacheReadOnly(readOnly bool) {
+	gb.cacheReadOnly = readOnly
+}
+
+// SetConcurrency sets how many projects BuildGraph analyzes at once (default:
+// the number of CPUs); values below 1 analyze one project at a time
+func (gb *GraphBuilder) SetConcurrency(workers int) {
+	gb.workers = max(workers, 1)
+}
+
+// BuildGraph scans all projects in the workspace and builds a dependency graph.
+// Projects are analyzed concurrently; when several fail, the error of the
+// first in projectDirs is returned.
 func (gb *GraphBuilder) BuildGraph(workspaceRoot string, projectDirs []string) (*dependencyscanner.DependencyGraph, error) {
+	return gb.buildGraph(workspaceRoot, projectDirs, nil)
+}
+
+// buildGraph is BuildGraph looking projects up in cache first, if not nil
+func (gb *GraphBuilder) buildGraph(workspaceRoot string, projectDirs []string, cache *graphCache) (*dependencyscanner.DependencyGraph, error) {
 	graph := dependencyscanner.NewDependencyGraph()
+	errs := make([]error, len(projectDirs))
 
-	for _, projectDir := range projectDirs {
-		projectPath := filepath.Join(workspaceRoot, projectDir)
+	var mu sync.Mutex
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < min(gb.workers, len(projectDirs)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				deps, err := gb.scanProject(workspaceRoot, projectDirs[index], cache)
+				if err != nil {
+					errs[index] = err
+					continue
+				}
+				if deps == nil {
+					continue
+				}
 
-		if !gb.scanner.CanScan(projectPath) {
-			continue
-		}
+				mu.Lock()
+				graph.AddProject(deps)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for index := range projectDirs {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
 
-		deps, err := AnalyzeProjectDependencies(projectPath)
+	for _, err := range errs {
 		if err != nil {
-			return nil, fmt.Errorf("failed to analyze project %s: %w", projectPath, err)
+			return nil, err
+		}
+	}
+	return graph, nil
+}
+
+// scanProject returns the dependencies of the project at projectDir, relative
+// to workspaceRoot, or nil when no registered scanner can scan it
+func (gb *GraphBuilder) scanProject(workspaceRoot, projectDir string, cache *graphCache) (*dependencyscanner.ProjectDependencies, error) {
+	projectPath := filepath.Join(workspaceRoot, projectDir)
+
+	var fingerprint string
+	if cache != nil {
+		fingerprint = gb.projectFingerprint(projectPath)
+		if deps, ok := cache.lookup(projectDir, fingerprint); ok {
+			return deps, nil
 		}
+	}
 
-		// Store relative path for better readability
-		deps.ProjectPath = projectDir
-		graph.AddProject(deps)
+	scanner, err := gb.registry.FindScanner(projectPath)
+	if err != nil {
+		return nil, nil
 	}
 
-	return graph, nil
+	deps, err := scanner.ScanProject(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze project %s: %w", projectPath, err)
+	}
+
+	// Store relative path for better readability
+	deps.ProjectPath = projectDir
+	if cache != nil && fingerprint != "" {
+		cache.store(projectDir, fingerprint, deps)
+	}
+	return deps, nil
 }
 
 // FindProjectDependencies finds which projects depend on a specific package
@@ -0,0 +1,205 @@
+package goscan
+
+import (
+	"crypto/sha256"
+	"duck/internal/dependencyscanner"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// GraphCacheFileName is the name of the dependency graph cache inside the
+// cache directory given to BuildGraphCached
+const GraphCacheFileName = "deps-graph.json"
+
+// graphCacheVersion is bumped when the analysis changes, so that graphs
+// cached by an older duck are analyzed again
+const graphCacheVersion = 1
+
+// graphCache stores the dependencies of each project keyed by its directory,
+// with the fingerprint of the files they were analyzed from
+type graphCache struct {
+	path     string
+	settings string
+	mu       sync.Mutex
+	entries  map[string]graphCacheEntry
+}
+
+type graphCacheEntry struct {
+	Fingerprint  string                                 `json:"fingerprint"`
+	Dependencies *dependencyscanner.ProjectDependencies `json:"dependencies"`
+}
+
+type graphCacheFile struct {
+	Settings string                     `json:"settings"` // Fingerprint of the GraphBuilder settings the entries were analyzed with
+	Entries  map[string]graphCacheEntry `json:"entries"`
+}
+
+// BuildGraphCached is BuildGraph reusing the dependencies cached in cacheDir
+// for the projects whose files did not change since; only the others are
+// analyzed again. A project is unchanged while its files (go.mod, Go sources,
+// package.json, ...) and the go.mod of its module keep their sizes and
+// modification times. The cache is updated afterwards unless it is read-only.
+func (gb *GraphBuilder) BuildGraphCached(workspaceRoot string, projectDirs []string, cacheDir string) (*dependencyscanner.DependencyGraph, error) {
+	cache := loadGraphCache(filepath.Join(cacheDir, GraphCacheFileName), gb.settingsFingerprint(workspaceRoot))
+
+	graph, err := gb.buildGraph(workspaceRoot, projectDirs, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	if !gb.cacheReadOnly {
+		cache.evict(workspaceRoot)
+		if err := cache.save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	return graph, nil
+}
+
+// loadGraphCache reads the cache at path. Entries written with different
+// settings are discarded.
+func loadGraphCache(path, settings string) *graphCache {
+	cache := &graphCache{
+		path:     path,
+		settings: settings,
+		entries:  make(map[string]graphCacheEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+
+	var file graphCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		// A corrupt cache is treated as empty and rewritten after the build
+		return cache
+	}
+	if file.Entries != nil && file.Settings == settings {
+		cache.entries = file.Entries
+	}
+
+	return cache
+}
+
+// lookup returns the cached dependencies of projectDir if its files are unchanged
+func (c *graphCache) lookup(projectDir, fingerprint string) (*dependencyscanner.ProjectDependencies, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[projectDir]
+	if !exists || fingerprint == "" || entry.Dependencies == nil || entry.Fingerprint != fingerprint {
+		return nil, false
+	}
+	return entry.Dependencies, true
+}
+
+// store records the freshly analyzed dependencies of projectDir
+func (c *graphCache) store(projectDir, fingerprint string, deps *dependencyscanner.ProjectDependencies) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[projectDir] = graphCacheEntry{Fingerprint: fingerprint, Dependencies: deps}
+}
+
+// evict drops the entries of projects whose directory no longer exists.
+// Entries of projects left out of this build are kept for the next one.
+func (c *graphCache) evict(workspaceRoot string) {
+	for projectDir := range c.entries {
+		if _, err := os.Stat(filepath.Join(workspaceRoot, projectDir)); err != nil {
+			delete(c.entries, projectDir)
+		}
+	}
+}
+
+// save writes the cache, creating its directory if needed
+func (c *graphCache) save() error {
+	data, err := json.Marshal(graphCacheFile{Settings: c.settings, Entries: c.entries})
+	if err != nil {
+		return fmt.Errorf("failed to encode dependency graph cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write dependency graph cache: %w", err)
+	}
+
+	return nil
+}
+
+// settingsFingerprint hashes what the analysis of every project depends on
+// besides its own files: the scanners, ignore rules and local packages, and
+// the workspace root that local replacements are resolved against
+func (gb *GraphBuilder) settingsFingerprint(workspaceRoot string) string {
+	localPackages := slices.Clone(gb.analyzer.localPackages)
+	slices.Sort(localPackages)
+
+	data, _ := json.Marshal(struct {
+		Version       int
+		WorkspaceRoot string
+		Languages     []string
+		Ignore        string
+		LocalPackages []string
+	}{
+		Version:       graphCacheVersion,
+		WorkspaceRoot: workspaceRoot,
+		Languages:     gb.languages,
+		Ignore:        gb.scanner.ignore.String(),
+		LocalPackages: localPackages,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// projectFingerprint hashes the path, size and modification time of the files
+// of the project at projectPath, skipping ignored directories, and of the
+// go.mod of the module it belongs to. It is empty if the project cannot be read.
+func (gb *GraphBuilder) projectFingerprint(projectPath string) string {
+	hash := sha256.New()
+	addFile := func(path string, info fs.FileInfo) {
+		fmt.Fprintf(hash, "%s\x00%s\x00%s\x00", path, strconv.FormatInt(info.Size(), 10), strconv.FormatInt(info.ModTime().UnixNano(), 10))
+	}
+
+	err := filepath.WalkDir(projectPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != projectPath && gb.scanner.ignore.Match(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		addFile(path, info)
+		return nil
+	})
+	if err != nil {
+		return ""
+	}
+
+	// Projects sharing the module of a parent directory take their package
+	// path from its go.mod
+	if _, moduleDir, err := FindModule(projectPath); err == nil {
+		goModPath := filepath.Join(moduleDir, "go.mod")
+		if info, err := os.Stat(goModPath); err == nil {
+			addFile(goModPath, info)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,85 @@
+package goscan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeBenchWorkspace generates projects Go modules under root, each with a few
+// source files importing external packages and the previous project, and
+// returns their directories relative to root
+func writeBenchWorkspace(b *testing.B, root string, projects int) []string {
+	b.Helper()
+
+	projectDirs := make([]string, 0, projects)
+	for i := 0; i < projects; i++ {
+		projectDir := filepath.Join("services", fmt.Sprintf("svc%d", i))
+		projectPath := filepath.Join(root, projectDir)
+		if err := os.MkdirAll(filepath.Join(projectPath, "internal", "handler"), 0755); err != nil {
+			b.Fatal(err)
+		}
+
+		goMod := fmt.Sprintf(`module example.com/services/svc%d
+
+go 1.23
+
+require (
+	github.com/google/uuid v1.6.0
+	github.com/urfave/cli/v2 v2.27.1
+	gopkg.in/yaml.v3 v3.0.1
+	github.com/cpuguy83/go-md2man/v2 v2.0.4 // indirect
+)
+`, i)
+		files := map[string]string{"go.mod": goMod}
+
+		imports := []string{`"fmt"`, `"os"`, `"github.com/google/uuid"`, `"github.com/urfave/cli/v2"`}
+		if i > 0 {
+			imports = append(imports, fmt.Sprintf(`"example.com/services/svc%d/internal/handler"`, i-1))
+		}
+		files["main.go"] = fmt.Sprintf("package main\n\nimport (\n\t%s\n)\n\nfunc main() {}\n", strings.Join(imports, "\n\t"))
+		for j := 0; j < 5; j++ {
+			files[filepath.Join("internal", "handler", fmt.Sprintf("handler%d.go", j))] =
+				"package handler\n\nimport (\n\t\"strings\"\n\n\t\"gopkg.in/yaml.v3\"\n)\n\nfunc Handle() {}\n"
+		}
+
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+		projectDirs = append(projectDirs, projectDir)
+	}
+	return projectDirs
+}
+
+// BenchmarkBuildGraph analyzes a generated workspace one project at a time
+// and with a few pool sizes
+func BenchmarkBuildGraph(b *testing.B) {
+	root := b.TempDir()
+	projectDirs := writeBenchWorkspace(b, root, 200)
+
+	localPackages := make([]string, len(projectDirs))
+	for i := range projectDirs {
+		localPackages[i] = fmt.Sprintf("example.com/services/svc%d", i)
+	}
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				gb := NewGraphBuilder()
+				gb.SetLocalPackages(localPackages)
+				gb.SetConcurrency(workers)
+				graph, err := gb.BuildGraph(root, projectDirs)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(graph.Projects) != len(projectDirs) {
+					b.Fatalf("graph has %d projects, want %d", len(graph.Projects), len(projectDirs))
+				}
+			}
+		})
+	}
+}
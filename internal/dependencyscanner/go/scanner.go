@@ -3,20 +3,30 @@ package goscan
 import (
 	"bufio"
 	"duck/internal/dependencyscanner"
+	"duck/internal/ignore"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
 // GoScanner implements the Scanner interface for Go projects
-type GoScanner struct{}
+type GoScanner struct {
+	ignore *ignore.Matcher
+}
 
 // NewGoScanner creates a new Go scanner instance
 func NewGoScanner() *GoScanner {
 	return &GoScanner{}
 }
 
+// SetIgnore sets the rules for directories skipped while scanning imports
+func (gs *GoScanner) SetIgnore(matcher *ignore.Matcher) {
+	gs.ignore = matcher
+}
+
 // GetLanguage returns the language this scanner supports
 func (gs *GoScanner) GetLanguage() string {
 	return "go"
@@ -46,7 +56,7 @@ func (gs *GoScanner) ScanProject(projectPath string) (*dependencyscanner.Project
 	}
 
 	scanner := bufio.NewScanner(file)
-	inRequireBlock := false
+	block := ""
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -56,82 +66,161 @@ func (gs *GoScanner) ScanProject(projectPath string) (*dependencyscanner.Project
 			continue
 		}
 
-		// Check for require block
-		if strings.HasPrefix(line, "require (") {
-			inRequireBlock = true
-			continue
-		} else if strings.HasPrefix(line, "require ") {
-			// Single line require
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				dep := gs.parseDependency(parts[1:])
-				if dep != nil {
-					deps.Dependencies = append(deps.Dependencies, *dep)
-				}
+		if block != "" {
+			// End of block
+			if stripComment(line) == ")" {
+				block = ""
+				continue
 			}
+
+			gs.parseDirective(deps, projectPath, block, line)
 			continue
 		}
 
-		// Check for replace block
-		if strings.HasPrefix(line, "replace (") {
-			continue
-		} else if strings.HasPrefix(line, "replace ") {
-			// We'll track replaces but not add them as separate dependencies
-			continue
+		verb := strings.Fields(line)[0]
+		rest := strings.TrimSpace(strings.TrimPrefix(line, verb))
+		if name, found := strings.CutSuffix(verb, "("); found && name != "" {
+			// Block opened without a space, e.g. "require("
+			verb, rest = name, "("
 		}
 
-		// End of block
-		if line == ")" {
-			inRequireBlock = false
+		// Start of a block such as "require (", "replace (", "exclude (" or "retract ("
+		if stripComment(rest) == "(" {
+			block = verb
 			continue
 		}
 
-		// Parse dependencies in require block
-		if inRequireBlock {
-			parts := strings.Fields(line)
-			if len(parts) >= 1 {
-				dep := gs.parseDependency(parts)
-				if dep != nil {
-					deps.Dependencies = append(deps.Dependencies, *dep)
-				}
-			}
-		}
+		gs.parseDirective(deps, projectPath, verb, rest)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error reading go.mod: %w", err)
 	}
 
+	// Point locally replaced dependencies at their directories
+	for i := range deps.Dependencies {
+		dep := &deps.Dependencies[i]
+		for _, replacement := range deps.Replacements {
+			if replacement.Old != dep.Target || replacement.LocalPath == "" {
+				continue
+			}
+			if replacement.OldVersion == "" || replacement.OldVersion == dep.Version {
+				dep.LocalPath = replacement.LocalPath
+				break
+			}
+		}
+	}
+
 	return deps, nil
 }
 
-// parseDependency parses a dependency from go.mod line parts
-func (gs *GoScanner) parseDependency(parts []string) *dependencyscanner.Dependency {
-	if len(parts) < 1 {
+// stripComment removes a trailing // comment from a go.mod line
+func stripComment(line string) string {
+	if idx := strings.Index(line, "//"); idx >= 0 {
+		line = line[:idx]
+	}
+	return strings.TrimSpace(line)
+}
+
+// parseDirective records a single require or replace directive, given without
+// its verb. Other directives, such as exclude and retract, are ignored.
+func (gs *GoScanner) parseDirective(deps *dependencyscanner.ProjectDependencies, projectPath, verb, directive string) {
+	switch verb {
+	case "require":
+		if dep := gs.parseDependency(strings.Fields(directive)); dep != nil {
+			deps.Dependencies = append(deps.Dependencies, *dep)
+		}
+	case "replace":
+		if replacement := gs.parseReplacement(projectPath, directive); replacement != nil {
+			deps.Replacements = append(deps.Replacements, *replacement)
+		}
+	}
+}
+
+// parseReplacement parses the "old [version] => new [version]" part of a replace directive
+func (gs *GoScanner) parseReplacement(projectPath, directive string) *dependencyscanner.Replacement {
+	// Drop trailing comments
+	if idx := strings.Index(directive, "//"); idx >= 0 {
+		directive = directive[:idx]
+	}
+
+	oldPart, newPart, found := strings.Cut(directive, "=>")
+	if !found {
 		return nil
 	}
 
-	target := parts[0]
-	version := ""
-	isDirect := true
+	oldFields := strings.Fields(oldPart)
+	newFields := strings.Fields(newPart)
+	if len(oldFields) == 0 || len(newFields) == 0 {
+		return nil
+	}
+
+	replacement := &dependencyscanner.Replacement{
+		Old: oldFields[0],
+		New: newFields[0],
+	}
+	if len(oldFields) >= 2 {
+		replacement.OldVersion = oldFields[1]
+	}
+	if len(newFields) >= 2 {
+		replacement.NewVersion = newFields[1]
+	}
 
-	if len(parts) >= 2 {
-		version = parts[1]
+	// Like the go command, only paths starting with ./, ../ or / are directories
+	if replacement.NewVersion == "" && isLocalModulePath(replacement.New) {
+		localPath := replacement.New
+		if !filepath.IsAbs(localPath) {
+			localPath = filepath.Join(projectPath, localPath)
+		}
+		if absPath, err := filepath.Abs(localPath); err == nil {
+			replacement.LocalPath = absPath
+		}
 	}
 
-	// Check if it's an indirect dependency
-	if len(parts) >= 3 && parts[2] == "//indirect" {
-		isDirect = false
+	return replacement
+}
+
+// isLocalModulePath reports whether a replacement refers to a directory
+func isLocalModulePath(path string) bool {
+	return filepath.IsAbs(path) ||
+		strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") ||
+		path == "." || path == ".."
+}
+
+// parseDependency parses a dependency from go.mod line parts. A trailing
+// "// indirect" comment (also written "//indirect" by older tools) marks the
+// dependency as indirect; other trailing comments are ignored.
+func (gs *GoScanner) parseDependency(parts []string) *dependencyscanner.Dependency {
+	line, comment, _ := strings.Cut(strings.Join(parts, " "), "//")
+
+	fields := strings.Fields(line)
+	if len(fields) < 1 {
+		return nil
+	}
+
+	target := fields[0]
+	version := ""
+
+	if len(fields) >= 2 {
+		version = fields[1]
 	}
 
 	return &dependencyscanner.Dependency{
 		Target:      target,
 		Version:     version,
-		IsDirect:    isDirect,
+		IsDirect:    !isIndirectComment(comment),
 		ImportPaths: []string{target},
 	}
 }
 
+// isIndirectComment reports whether the text of a trailing go.mod comment marks
+// a requirement as indirect. Like the go command, it accepts "indirect" on its
+// own or followed by "; other text".
+func isIndirectComment(comment string) bool {
+	comment = strings.TrimSpace(comment)
+	return comment == "indirect" || strings.HasPrefix(comment, "indirect;")
+}
+
 // ScanImports scans all Go files in a project and returns actual import statements
 // This is useful for finding which dependencies are actually used
 func (gs *GoScanner) ScanImports(projectPath string) ([]string, error) {
@@ -142,6 +231,10 @@ func (gs *GoScanner) ScanImports(projectPath string) ([]string, error) {
 			return err
 		}
 
+		if info.IsDir() && path != projectPath && gs.ignore.Match(path, true) {
+			return filepath.SkipDir
+		}
+
 		// Skip directories and non-Go files
 		if info.IsDir() || !strings.HasSuffix(path, ".go") {
 			return nil
@@ -177,6 +270,28 @@ func (gs *GoScanner) ScanImports(projectPath string) ([]string, error) {
 	return result, nil
 }
 
+// containsGoFiles reports whether projectPath or a directory below it that is
+// not ignored holds a Go file
+func (gs *GoScanner) containsGoFiles(projectPath string) bool {
+	found := errors.New("found")
+	err := filepath.WalkDir(projectPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != projectPath && gs.ignore.Match(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".go") {
+			return found
+		}
+		return nil
+	})
+	return err == found
+}
+
 // parseImportsFromFile extracts import statements from a Go file
 func (gs *GoScanner) parseImportsFromFile(filePath string) ([]string, error) {
 	file, err := os.Open(filePath)
@@ -0,0 +1,107 @@
+package goscan
+
+import (
+	"testing"
+
+	"duck/internal/dependencyscanner"
+)
+
+// scanFixture parses the go.mod of testdata/name
+func scanFixture(t *testing.T, name string) *dependencyscanner.ProjectDependencies {
+	t.Helper()
+	deps, err := NewGoScanner().ScanProject("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deps
+}
+
+// dependencyMap indexes dependencies by target
+func dependencyMap(deps *dependencyscanner.ProjectDependencies) map[string]dependencyscanner.Dependency {
+	byTarget := make(map[string]dependencyscanner.Dependency, len(deps.Dependencies))
+	for _, dep := range deps.Dependencies {
+		byTarget[dep.Target] = dep
+	}
+	return byTarget
+}
+
+func TestScanProjectIndirectComments(t *testing.T) {
+	deps := dependencyMap(scanFixture(t, "indirect"))
+
+	tests := []struct {
+		target     string
+		version    string
+		wantDirect bool
+	}{
+		{"github.com/direct/one", "v1.0.0", true},
+		{"github.com/tidy/spaced", "v1.1.0", false},
+		{"github.com/legacy/unspaced", "v1.2.0", false},
+		{"github.com/tidy/reason", "v1.3.0", false},
+		{"github.com/commented/direct", "v1.4.0", true},
+		{"github.com/single/indirect", "v2.0.0", false},
+	}
+
+	if len(deps) != len(tests) {
+		t.Errorf("got %d dependencies, want %d: %v", len(deps), len(tests), deps)
+	}
+	for _, tt := range tests {
+		dep, exists := deps[tt.target]
+		if !exists {
+			t.Errorf("%s: not found", tt.target)
+			continue
+		}
+		if dep.Version != tt.version {
+			t.Errorf("%s: version = %q, want %q", tt.target, dep.Version, tt.version)
+		}
+		if dep.IsDirect != tt.wantDirect {
+			t.Errorf("%s: IsDirect = %v, want %v", tt.target, dep.IsDirect, tt.wantDirect)
+		}
+	}
+}
+
+func TestScanProjectInterleavedBlocks(t *testing.T) {
+	deps := scanFixture(t, "blocks")
+
+	// Excluded modules and retracted versions must not leak into the requirements
+	wantRequires := map[string]string{
+		"github.com/first/req":    "v1.0.0",
+		"github.com/second/req":   "v1.1.0",
+		"github.com/single/req":   "v0.3.0",
+		"github.com/unspaced/req": "v1.5.0",
+	}
+	requires := dependencyMap(deps)
+	if len(requires) != len(wantRequires) {
+		t.Errorf("got %d requirements, want %d: %v", len(requires), len(wantRequires), requires)
+	}
+	for target, version := range wantRequires {
+		if dep, exists := requires[target]; !exists {
+			t.Errorf("%s: not found", target)
+		} else if dep.Version != version {
+			t.Errorf("%s: version = %q, want %q", target, dep.Version, version)
+		}
+	}
+	if requires["github.com/second/req"].IsDirect {
+		t.Errorf("github.com/second/req: IsDirect = true, want false")
+	}
+
+	wantReplacements := []dependencyscanner.Replacement{
+		{Old: "github.com/first/req", New: "../first"},
+		{Old: "github.com/second/req", OldVersion: "v1.1.0", New: "github.com/fork/req", NewVersion: "v1.1.1"},
+		{Old: "github.com/single/req", New: "github.com/single/fork", NewVersion: "v0.3.1"},
+	}
+	if len(deps.Replacements) != len(wantReplacements) {
+		t.Fatalf("got %d replacements, want %d: %+v", len(deps.Replacements), len(wantReplacements), deps.Replacements)
+	}
+	for i, want := range wantReplacements {
+		got := deps.Replacements[i]
+		got.LocalPath = ""
+		if got != want {
+			t.Errorf("replacement %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	// The local replacement points the requirement at its directory
+	if requires["github.com/first/req"].LocalPath == "" {
+		t.Errorf("github.com/first/req: LocalPath is empty, want the ../first directory")
+	}
+}
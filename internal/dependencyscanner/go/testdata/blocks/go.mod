@@ -0,0 +1,33 @@
+module example.com/blocks
+
+go 1.23
+
+require (
+	github.com/first/req v1.0.0
+	github.com/second/req v1.1.0 // indirect
+)
+
+replace (
+	github.com/first/req => ../first
+	github.com/second/req v1.1.0 => github.com/fork/req v1.1.1
+)
+
+require github.com/single/req v0.3.0
+
+exclude (
+	github.com/excluded/mod v0.1.0
+	github.com/excluded/other v0.2.0
+)
+
+replace github.com/single/req => github.com/single/fork v0.3.1
+
+retract (
+	v0.9.0 // published by mistake
+	[v0.5.0, v0.6.0]
+)
+
+require(
+	github.com/unspaced/req v1.5.0
+)
+
+exclude github.com/excluded/single v0.4.0
@@ -0,0 +1,13 @@
+module example.com/indirect
+
+go 1.23
+
+require (
+	github.com/direct/one v1.0.0
+	github.com/tidy/spaced v1.1.0 // indirect
+	github.com/legacy/unspaced v1.2.0 //indirect
+	github.com/tidy/reason v1.3.0 // indirect; pulled in by one
+	github.com/commented/direct v1.4.0 // pinned until the API settles
+)
+
+require github.com/single/indirect v2.0.0 // indirect
@@ -0,0 +1,159 @@
+package goscan
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WorkspaceModule is a module listed in a go.work file
+type WorkspaceModule struct {
+	Path string // Module path declared in the module's go.mod
+	Dir  string // Absolute directory of the module
+}
+
+// ParseGoWork reads the use directives of a go.work file and returns the member
+// modules with their module paths
+func ParseGoWork(goWorkPath string) ([]WorkspaceModule, error) {
+	file, err := os.Open(goWorkPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open go.work: %w", err)
+	}
+	defer file.Close()
+
+	workDir, err := filepath.Abs(filepath.Dir(goWorkPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for go.work: %w", err)
+	}
+
+	var dirs []string
+	scanner := bufio.NewScanner(file)
+	inUseBlock := false
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Drop comments
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "use (") || line == "use(" {
+			inUseBlock = true
+			continue
+		} else if strings.HasPrefix(line, "use ") {
+			dirs = append(dirs, unquote(strings.TrimSpace(strings.TrimPrefix(line, "use "))))
+			continue
+		}
+
+		if line == ")" {
+			inUseBlock = false
+			continue
+		}
+
+		if inUseBlock {
+			dirs = append(dirs, unquote(line))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading go.work: %w", err)
+	}
+
+	modules := make([]WorkspaceModule, 0, len(dirs))
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workDir, dir)
+		}
+
+		modulePath, err := ReadModulePath(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+
+		modules = append(modules, WorkspaceModule{Path: modulePath, Dir: filepath.Clean(dir)})
+	}
+
+	return modules, nil
+}
+
+// FindModule returns the module path and directory of the module containing
+// dir: the nearest directory at or above it with a go.mod
+func FindModule(dir string) (string, string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
+	}
+
+	for current := absDir; ; current = filepath.Dir(current) {
+		goModPath := filepath.Join(current, "go.mod")
+		if _, err := os.Stat(goModPath); err == nil {
+			modulePath, err := ReadModulePath(goModPath)
+			if err != nil {
+				return "", "", err
+			}
+			return modulePath, current, nil
+		}
+		if filepath.Dir(current) == current {
+			return "", "", fmt.Errorf("no go.mod found at or above %s", absDir)
+		}
+	}
+}
+
+// PackagePath returns the import path of the package in dir, from the module
+// containing it
+func PackagePath(dir string) (string, error) {
+	modulePath, moduleDir, err := FindModule(dir)
+	if err != nil {
+		return "", err
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path for %s: %w", dir, err)
+	}
+	relPath, err := filepath.Rel(moduleDir, absDir)
+	if err != nil {
+		return "", err
+	}
+	if relPath == "." {
+		return modulePath, nil
+	}
+	return modulePath + "/" + filepath.ToSlash(relPath), nil
+}
+
+// ReadModulePath returns the module path declared in a go.mod file
+func ReadModulePath(goModPath string) (string, error) {
+	file, err := os.Open(goModPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open go.mod: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return unquote(strings.TrimSpace(strings.TrimPrefix(line, "module "))), nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("error reading go.mod: %w", err)
+	}
+
+	return "", fmt.Errorf("no module directive in %s", goModPath)
+}
+
+// unquote strips the quotes go.mod and go.work allow around paths
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '`') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -2,6 +2,7 @@ package dependencyscanner
 
 import (
 	"fmt"
+	"sort"
 )
 
 // Dependency represents a single dependency
@@ -11,13 +12,25 @@ type Dependency struct {
 	Version     string   // Version of the dependency (if available)
 	IsDirect    bool     // Whether it's a direct or indirect dependency
 	ImportPaths []string // Specific import paths used
+	Via         []string // For indirect dependencies, the direct dependencies that pull it in (if known)
+	LocalPath   string   // Absolute directory the dependency is replaced with, for local replacements
+}
+
+// Replacement represents a module replaced with another module or a local directory
+type Replacement struct {
+	Old        string // The replaced module
+	OldVersion string // Version of the replaced module (empty if all versions are replaced)
+	New        string // The replacement module or directory
+	NewVersion string // Version of the replacement (empty for directories)
+	LocalPath  string // Absolute path of the replacement directory, for local replacements
 }
 
 // ProjectDependencies represents all dependencies for a project
 type ProjectDependencies struct {
-	ProjectPath  string       // Path to the project
-	Language     string       // Programming language
-	Dependencies []Dependency // List of dependencies
+	ProjectPath  string        // Path to the project
+	Language     string        // Programming language
+	Dependencies []Dependency  // List of dependencies
+	Replacements []Replacement // Replace directives (if the language has them)
 }
 
 // Scanner is the interface that all language-specific scanners must implement
@@ -66,3 +79,61 @@ func (dg *DependencyGraph) GetProjectsWithDependencies() []*ProjectDependencies
 	}
 	return result
 }
+
+// FindDependents returns the paths of the projects that depend on target
+func (dg *DependencyGraph) FindDependents(target string) []string {
+	dependents := make([]string, 0)
+
+	for _, project := range dg.Projects {
+		for _, dep := range project.Dependencies {
+			if dep.Target == target {
+				dependents = append(dependents, project.ProjectPath)
+				break
+			}
+		}
+	}
+
+	sort.Strings(dependents)
+	return dependents
+}
+
+// VersionConflict is a dependency required at different versions by different projects
+type VersionConflict struct {
+	Target   string              // The dependency
+	Versions map[string][]string // Version -> sorted paths of the projects requiring it
+}
+
+// FindVersionConflicts returns the dependencies that projects in the graph require
+// at more than one version, sorted by target
+func (dg *DependencyGraph) FindVersionConflicts() []VersionConflict {
+	versions := make(map[string]map[string][]string)
+
+	for _, project := range dg.Projects {
+		for _, dep := range project.Dependencies {
+			if dep.Version == "" {
+				continue
+			}
+			if versions[dep.Target] == nil {
+				versions[dep.Target] = make(map[string][]string)
+			}
+			versions[dep.Target][dep.Version] = append(versions[dep.Target][dep.Version], project.ProjectPath)
+		}
+	}
+
+	var conflicts []VersionConflict
+	for target, byVersion := range versions {
+		if len(byVersion) < 2 {
+			continue
+		}
+		for _, projects := range byVersion {
+			sort.Strings(projects)
+		}
+		conflicts = append(conflicts, VersionConflict{Target: target, Versions: byVersion})
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Target < conflicts[j].Target
+	})
+
+	return conflicts
+}
@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"duck/internal/config"
+)
+
+// recordArtifacts copies the files matching Options.RecordArtifacts into
+// Options.ArtifactsDir/<projectKey>/, keeping their paths relative to the
+// project root. Matched directories are copied with their contents. It returns
+// the copied paths relative to the project's artifacts directory.
+func (e *Executor) recordArtifacts(projectKey string, project *config.AppProject) ([]string, error) {
+	pattern := e.replaceVariables(e.options.RecordArtifacts, project, project.Path)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(project.Path, pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifacts pattern %q: %w", e.options.RecordArtifacts, err)
+	}
+
+	destinationRoot := filepath.Join(e.options.ArtifactsDir, filepath.FromSlash(projectKey))
+
+	var recorded []string
+	for _, match := range matches {
+		err := filepath.WalkDir(match, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+
+			rel := artifactPath(project.Path, match, path)
+			if err := copyFile(path, filepath.Join(destinationRoot, rel)); err != nil {
+				return err
+			}
+			recorded = append(recorded, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return recorded, fmt.Errorf("failed to record artifact %s: %w", match, err)
+		}
+	}
+
+	return recorded, nil
+}
+
+// artifactPath returns where path, found under the glob match, is stored below
+// the project's artifacts directory. Matches outside the project root are
+// stored under their base name.
+func artifactPath(projectRoot, match, path string) string {
+	rel, err := filepath.Rel(projectRoot, path)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel
+	}
+
+	rel, err = filepath.Rel(filepath.Dir(match), path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return rel
+}
+
+func copyFile(source, destination string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -2,12 +2,15 @@ package executor
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -21,39 +24,168 @@ type ExecutionResult struct {
 	Success    bool
 	Output     string
 	Error      string
-	Duration   time.Duration
+	// ExitCode is the exit code of the last attempt: 0 on success, -1 when the
+	// command did not start, and 128+n when it was killed by signal n
+	ExitCode int
+	Attempts int
+	Duration time.Duration
+	// SlowWarning is set when the script ran longer than Options.MaxRuntime
+	SlowWarning bool
+	// Artifacts lists the files copied by Options.RecordArtifacts, relative to
+	// the project's artifacts directory
+	Artifacts []string
+	// Steps are the results of the last attempt's steps, for scripts with commands
+	Steps []StepResult
+	// Service is set when the script has readyWhen and was left running
+	Service *Service
+}
+
+// StepResult is the outcome of one of a script's commands. Steps after the
+// first failing one are not run and have no result.
+type StepResult struct {
+	Command  string
+	Success  bool
+	Output   string
+	Error    string
+	ExitCode int
+	Duration time.Duration
+}
+
+// EventType identifies what an Event reports
+type EventType string
+
+const (
+	// EventStart is emitted when a project starts running a script
+	EventStart EventType = "start"
+	// EventOutput is emitted for every line the script or a hook writes
+	EventOutput EventType = "output"
+	// EventFinish is emitted when a project is done, with its result
+	EventFinish EventType = "finish"
+)
+
+// Event reports the progress of ExecuteScript to Options.OnEvent
+type Event struct {
+	Type       EventType
+	ProjectKey string
+	Script     string
+	// Stream is "stdout", "stderr" or, with OutputCombined, "combined"; set for EventOutput
+	Stream string
+	Line   string
+	// Result is set for EventFinish
+	Result *ExecutionResult
+}
+
+// OutputMode controls how a command's stdout and stderr are captured
+type OutputMode string
+
+const (
+	// OutputSeparate captures stdout as Output and stderr as Error
+	OutputSeparate OutputMode = "separate"
+	// OutputCombined interleaves stdout and stderr into Output in the order they were written
+	OutputCombined OutputMode = "combined"
+	// OutputInteractive connects the command to duck's stdin, stdout and stderr
+	// instead of capturing its output, which leaves Output and Error empty
+	OutputInteractive OutputMode = "interactive"
+)
+
+// WorkingDirStrategy selects the directory scripts run in
+type WorkingDirStrategy string
+
+const (
+	// WorkingDirProject runs scripts in their workingDir, relative to the project root
+	WorkingDirProject WorkingDirStrategy = "project"
+	// WorkingDirWorkspace runs scripts in the workspace root, ignoring their workingDir
+	WorkingDirWorkspace WorkingDirStrategy = "workspace"
+)
+
+// Options holds optional settings that tune how scripts are executed
+type Options struct {
+	// Environment is applied on top of the script and project environment
+	Environment map[string]string
+	// BeforeEach is a command run in each project's directory before the script
+	BeforeEach string
+	// AfterEach is a command run in each project's directory after the script,
+	// even when the script or BeforeEach failed
+	AfterEach string
+	// MaxRuntime is the expected upper bound for a script's duration. Exceeding it
+	// only sets SlowWarning on the result; the script is not stopped.
+	MaxRuntime time.Duration
+	// OutputMode selects how output is captured; the zero value means OutputSeparate
+	OutputMode OutputMode
+	// Timeout, when positive, overrides the timeout of every script and project
+	Timeout time.Duration
+	// Retries, when set, overrides the retries of every script and project
+	Retries *int
+	// RecordArtifacts is a glob, relative to the project root, of files copied
+	// to ArtifactsDir/<project key>/ after each successful run
+	RecordArtifacts string
+	// ArtifactsDir is the directory artifacts are collected in
+	ArtifactsDir string
+	// WorkingDirStrategy selects where scripts run; the zero value means WorkingDirProject
+	WorkingDirStrategy WorkingDirStrategy
+	// WorkspaceRoot is the directory containing duck.yaml. Scripts run there with
+	// WorkingDirWorkspace and {workspaceRoot} expands to it. Defaults to the
+	// current directory.
+	WorkspaceRoot string
+	// IsolateEnv starts every script from an empty environment instead of
+	// duck's own, as if each script set isolateEnv
+	IsolateEnv bool
+	// EnvAllowlist names the variables of duck's environment kept for isolated scripts
+	EnvAllowlist []string
+	// OnEvent, when set, is called as scripts start, write output and finish.
+	// Output events of stdout and stderr may arrive concurrently. Nothing is
+	// reported for OutputInteractive output, which goes to the terminal.
+	OnEvent func(Event)
 }
 
 type Executor struct {
 	projectConfig *config.ProjectConfig
 	projects      map[string]*config.AppProject
+	options       Options
+
+	servicesMu sync.Mutex
+	services   []*Service // Running services, in the order they became ready
 }
 
 func New(projectConfig *config.ProjectConfig, projects map[string]*config.AppProject) *Executor {
+	return NewWithOptions(projectConfig, projects, Options{})
+}
+
+func NewWithOptions(projectConfig *config.ProjectConfig, projects map[string]*config.AppProject, options Options) *Executor {
+	if options.WorkspaceRoot == "" {
+		if cwd, err := os.Getwd(); err == nil {
+			options.WorkspaceRoot = cwd
+		}
+	}
+
 	return &Executor{
 		projectConfig: projectConfig,
 		projects:      projects,
+		options:       options,
 	}
 }
 
 func (e *Executor) ExecuteScript(ctx context.Context, projectKey, scriptName string) (*ExecutionResult, error) {
-	project, exists := e.projects[projectKey]
-	if !exists {
-		return nil, fmt.Errorf("project %s not found", projectKey)
+	project, script, err := e.lookup(projectKey, scriptName)
+	if err != nil {
+		return nil, err
 	}
 
-	script, exists := e.projectConfig.Scripts[scriptName]
-	if !exists {
-		return nil, fmt.Errorf("script %s not found", scriptName)
+	shell, err := e.resolveShell(script.Shell)
+	if err != nil {
+		return nil, fmt.Errorf("script %s: %w", scriptName, err)
+	}
+	if script.ReadyWhen != "" && e.options.OutputMode == OutputInteractive {
+		return nil, fmt.Errorf("script %s: readyWhen cannot be used with interactive output", scriptName)
 	}
 
-	if enabled, exists := project.Config.Scripts[scriptName]; exists && !enabled {
-		return &ExecutionResult{
-			ProjectKey: projectKey,
-			Script:     scriptName,
-			Success:    false,
-			Error:      "script disabled for this project",
-		}, nil
+	// Hooks are not tied to a script, so they always use the workspace shell
+	var hookShell []string
+	if e.options.BeforeEach != "" || e.options.AfterEach != "" {
+		hookShell, err = e.resolveShell("")
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	result := &ExecutionResult{
@@ -61,50 +193,381 @@ func (e *Executor) ExecuteScript(ctx context.Context, projectKey, scriptName str
 		Script:     scriptName,
 	}
 
+	e.emit(Event{Type: EventStart, ProjectKey: projectKey, Script: scriptName})
 	start := time.Now()
 	defer func() {
 		result.Duration = time.Since(start)
+		result.SlowWarning = e.options.MaxRuntime > 0 && result.Duration > e.options.MaxRuntime
+		e.emit(Event{Type: EventFinish, ProjectKey: projectKey, Script: scriptName, Result: result})
 	}()
 
-	workingDir := project.Path
-	if script.WorkingDir != "" {
-		expandedWorkingDir := e.replaceVariables(script.WorkingDir, project, project.Path)
+	if !project.Config.ScriptEnabled(scriptName) {
+		result.Error = "script disabled for this project"
+		result.ExitCode = -1
+		return result, nil
+	}
+
+	onLine := e.outputSink(projectKey, scriptName)
+	workingDir := e.resolveWorkingDir(script, project)
+	commands := e.resolveSteps(script, project, workingDir)
+	env := Environ(e.resolveEnvironment(script, project))
+
+	if e.options.AfterEach != "" {
+		defer e.runHook(ctx, hookShell, "after-each", e.options.AfterEach, project, env, result, onLine)
+	}
 
-		if filepath.IsAbs(expandedWorkingDir) {
-			workingDir = expandedWorkingDir
-		} else {
-			workingDir = filepath.Join(project.Path, expandedWorkingDir)
+	if e.options.BeforeEach != "" {
+		if !e.runHook(ctx, hookShell, "before-each", e.options.BeforeEach, project, env, result, onLine) {
+			return result, nil
 		}
 	}
 
-	command := e.replaceVariables(script.Command, project, workingDir)
+	timeout, retries := e.scriptSettings(script, project, scriptName)
 
-	cmd := exec.CommandContext(ctx, "sh", "-c", command)
-	cmd.Dir = workingDir
+	if script.ReadyWhen != "" {
+		hookOutput := result.Output
+		e.startService(ctx, result, shell, commands[0], workingDir, env, script.ReadyWhen, timeout, onLine)
+		result.Output = hookOutput + result.Output
+		return result, nil
+	}
+
+	hookOutput := result.Output
+	for {
+		result.Attempts++
+		attempt := e.runAttempt(ctx, shell, commands, workingDir, env, timeout, onLine)
+		result.Success = attempt.Success
+		result.Output = hookOutput + attempt.Output
+		result.Error = attempt.Error
+		result.ExitCode = attempt.ExitCode
+		if len(script.Commands) > 0 {
+			result.Steps = attempt.Steps
+		}
+
+		if result.Success || result.Attempts > retries || !script.ShouldRetry(result.ExitCode) {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if result.Success && e.options.RecordArtifacts != "" {
+		artifacts, err := e.recordArtifacts(projectKey, project)
+		result.Artifacts = artifacts
+		if err != nil {
+			result.Success = false
+			result.Error = err.Error()
+		}
+	}
+
+	return result, nil
+}
+
+// ResolveCommand returns what ExecuteScript would run for projectKey without
+// running it: the command with its variables substituted, the directory it
+// runs in and its environment as KEY=value entries (see Environ). The steps
+// of a script with commands are joined with " && ", which also stops at the
+// first that fails. Environment variables such as $HOME in the command are
+// left for the shell to expand.
+func (e *Executor) ResolveCommand(projectKey, scriptName string) (string, string, []string, error) {
+	project, script, err := e.lookup(projectKey, scriptName)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	workingDir := e.resolveWorkingDir(script, project)
+	command := strings.Join(e.resolveSteps(script, project, workingDir), " && ")
+	return command, workingDir, Environ(e.resolveEnvironment(script, project)), nil
+}
+
+// lookup returns the project and script to run, with the project's overrides
+// of the script applied
+func (e *Executor) lookup(projectKey, scriptName string) (*config.AppProject, config.Script, error) {
+	project, exists := e.projects[projectKey]
+	if !exists {
+		return nil, config.Script{}, fmt.Errorf("project %s not found", projectKey)
+	}
+
+	script, exists := e.projectConfig.Scripts[scriptName]
+	if !exists {
+		return nil, config.Script{}, fmt.Errorf("script %s not found", scriptName)
+	}
+
+	if override, exists := project.Config.Scripts[scriptName]; exists {
+		script = override.Apply(script)
+		if err := script.Validate(); err != nil {
+			return nil, config.Script{}, fmt.Errorf("script %s as overridden by project %s: %w", scriptName, projectKey, err)
+		}
+	}
+
+	return project, script, nil
+}
+
+// resolveSteps returns the commands of script with their variables substituted
+func (e *Executor) resolveSteps(script config.Script, project *config.AppProject, workingDir string) []string {
+	var commands []string
+	for _, step := range script.Steps() {
+		commands = append(commands, e.replaceVariables(step, project, workingDir))
+	}
+	return commands
+}
+
+// resolveEnvironment returns the environment script runs with in project.
+// Each layer overrides the variables of the ones before it: the base
+// environment, duck.yaml's environment, the project's, the script's, and
+// finally --env-file/--env. The script's layer is its environment in duck.yaml
+// merged with the environment of the project's override of that script, which
+// wins over duck.yaml.
+func (e *Executor) resolveEnvironment(script config.Script, project *config.AppProject) map[string]string {
+	env := e.baseEnvironment(script)
+	for _, layer := range []map[string]string{
+		e.projectConfig.Environment,
+		project.Config.Environment,
+		script.Environment,
+		e.options.Environment,
+	} {
+		for key, value := range layer {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// Environ renders env as the KEY=value entries of a process environment,
+// sorted by name so that commands see the same environment on every run
+func Environ(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, key+"="+env[key])
+	}
+	return entries
+}
+
+// resolveWorkingDir returns the directory script runs in for project. With
+// WorkingDirWorkspace it is always the workspace root; otherwise the script's
+// workingDir, resolved relative to the project root, or the project root itself.
+func (e *Executor) resolveWorkingDir(script config.Script, project *config.AppProject) string {
+	if e.options.WorkingDirStrategy == WorkingDirWorkspace {
+		return e.options.WorkspaceRoot
+	}
+
+	if script.WorkingDir == "" {
+		return project.Path
+	}
+
+	expandedWorkingDir := e.replaceVariables(script.WorkingDir, project, project.Path)
+	if filepath.IsAbs(expandedWorkingDir) {
+		return expandedWorkingDir
+	}
+	return filepath.Join(project.Path, expandedWorkingDir)
+}
+
+// baseEnvironment returns the environment script starts from before the
+// configured variables are applied: duck's own environment, or only its
+// allowlisted variables when the environment is isolated
+func (e *Executor) baseEnvironment(script config.Script) map[string]string {
+	env := make(map[string]string)
+	if !e.options.IsolateEnv && !script.IsolateEnv {
+		for _, entry := range os.Environ() {
+			// The name ends at the first '=' after its first character, as
+			// Windows has entries such as "=C:=C:\"
+			if i := strings.Index(entry[1:], "="); i >= 0 {
+				env[entry[:i+1]] = entry[i+2:]
+			}
+		}
+		return env
+	}
+
+	for _, key := range e.options.EnvAllowlist {
+		if value, ok := os.LookupEnv(key); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// resolveShell returns the program and arguments that run a command: the
+// script's shell, falling back to the workspace shell and then defaultShell.
+func (e *Executor) resolveShell(scriptShell string) ([]string, error) {
+	if scriptShell != "" {
+		return LookupShell(scriptShell)
+	}
+	return LookupShell(e.projectConfig.Shell)
+}
+
+// LookupShell splits a shell setting such as "bash -c" into the program and
+// arguments that run a command, defaulting to "sh -c" ("cmd /c" on Windows)
+// when shell is empty. The program must be found on PATH.
+func LookupShell(shell string) ([]string, error) {
+	args := defaultShell
+	if shell != "" {
+		args = strings.Fields(shell)
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("shell must not be blank")
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, fmt.Errorf("shell %s not found: %w", args[0], err)
+	}
+
+	return args, nil
+}
 
-	cmd.Env = os.Environ()
-	for key, value := range script.Environment {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+// scriptSettings returns the timeout and retries for running script on project.
+// The executor options take precedence over the project's scriptSettings, which
+// take precedence over the script in duck.yaml.
+func (e *Executor) scriptSettings(script config.Script, project *config.AppProject, scriptName string) (time.Duration, int) {
+	timeout, retries := script.Timeout, script.Retries
+
+	if settings, exists := project.Config.ScriptSettings[scriptName]; exists {
+		if settings.Timeout > 0 {
+			timeout = settings.Timeout
+		}
+		if settings.Retries != nil {
+			retries = *settings.Retries
+		}
+	}
+
+	if e.options.Timeout > 0 {
+		timeout = e.options.Timeout
+	}
+	if e.options.Retries != nil {
+		retries = *e.options.Retries
+	}
+
+	return timeout, retries
+}
+
+// runAttempt runs commands once, stopping them when they run longer than timeout
+func (e *Executor) runAttempt(ctx context.Context, shell []string, commands []string, workingDir string, env []string, timeout time.Duration, onLine lineSink) *ExecutionResult {
+	if timeout <= 0 {
+		return e.runSteps(ctx, shell, commands, workingDir, env, onLine)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := e.runSteps(attemptCtx, shell, commands, workingDir, env, onLine)
+	if !result.Success && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		message := fmt.Sprintf("timed out after %v", timeout)
+		if strings.TrimSpace(result.Error) != "" {
+			message += "\n" + result.Error
+		}
+		result.Error = message
 	}
-	for key, value := range project.Config.Environment {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+
+	return result
+}
+
+// runSteps runs commands one after another, stopping at the first that fails.
+// The result carries the combined output and the exit code of the last step run.
+func (e *Executor) runSteps(ctx context.Context, shell []string, commands []string, workingDir string, env []string, onLine lineSink) *ExecutionResult {
+	result := &ExecutionResult{Success: true}
+	for i, command := range commands {
+		start := time.Now()
+		step := e.runCommand(ctx, shell, command, workingDir, env, onLine)
+		step.Duration = time.Since(start)
+
+		result.Steps = append(result.Steps, stepResult(command, step))
+		result.Output += step.Output
+		result.Error += step.Error
+		result.ExitCode = step.ExitCode
+		if !step.Success {
+			result.Success = false
+			if len(commands) > 1 {
+				message := fmt.Sprintf("step %d/%d failed: %s", i+1, len(commands), command)
+				if strings.TrimSpace(result.Error) != "" {
+					message += "\n" + result.Error
+				}
+				result.Error = message
+			}
+			break
+		}
+	}
+
+	return result
+}
+
+func stepResult(command string, result *ExecutionResult) StepResult {
+	return StepResult{
+		Command:  command,
+		Success:  result.Success,
+		Output:   result.Output,
+		Error:    result.Error,
+		ExitCode: result.ExitCode,
+		Duration: result.Duration,
+	}
+}
+
+// runHook runs a --before-each/--after-each command in the project directory and
+// folds its output into result. A failing hook marks the whole result as failed.
+func (e *Executor) runHook(ctx context.Context, shell []string, name, command string, project *config.AppProject, env []string, result *ExecutionResult, onLine lineSink) bool {
+	hook := e.runCommand(ctx, shell, e.replaceVariables(command, project, project.Path), project.Path, env, onLine)
+
+	result.Output += hook.Output
+	if hook.Success {
+		return true
+	}
+
+	message := fmt.Sprintf("%s hook failed: %s", name, strings.TrimSpace(hook.Error))
+	if result.Error != "" && !result.Success {
+		result.Error += "\n" + message
+	} else {
+		result.Error = message
+	}
+	result.Success = false
+	result.ExitCode = hook.ExitCode
+
+	return false
+}
+
+// runCommand runs command once with shell and returns its outcome. Only
+// Success, Output, Error and ExitCode are set on the returned result. Captured
+// lines are also passed to onLine, if set.
+func (e *Executor) runCommand(ctx context.Context, shell []string, command, workingDir string, env []string, onLine lineSink) *ExecutionResult {
+	result := &ExecutionResult{ExitCode: -1}
+
+	args := append(append([]string(nil), shell[1:]...), command)
+	cmd := exec.CommandContext(ctx, shell[0], args...)
+	cmd.Dir = workingDir
+	cmd.Env = env
+	if ctx.Done() != nil && e.options.OutputMode != OutputInteractive {
+		// Stop the children of the shell too when the attempt times out or
+		// duck is interrupted. Interactive commands stay in duck's process
+		// group, as a background process group cannot read the terminal, and
+		// receive Ctrl-C from the terminal directly.
+		killProcessGroup(cmd)
+	}
+
+	switch e.options.OutputMode {
+	case OutputCombined:
+		return runCombined(cmd, result, onLine)
+	case OutputInteractive:
+		return runInteractive(cmd, result)
 	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		result.Error = fmt.Sprintf("failed to create stdout pipe: %v", err)
-		return result, nil
+		return result
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		result.Error = fmt.Sprintf("failed to create stderr pipe: %v", err)
-		return result, nil
+		return result
 	}
 
 	if err := cmd.Start(); err != nil {
 		result.Error = fmt.Sprintf("failed to start command: %v", err)
-		return result, nil
+		return result
 	}
 
 	var outputBuilder, errorBuilder strings.Builder
@@ -113,12 +576,12 @@ func (e *Executor) ExecuteScript(ctx context.Context, projectKey, scriptName str
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		copyOutput(stdout, &outputBuilder)
+		copyOutput(stdout, &outputBuilder, onLine.stream("stdout"))
 	}()
 
 	go func() {
 		defer wg.Done()
-		copyOutput(stderr, &errorBuilder)
+		copyOutput(stderr, &errorBuilder, onLine.stream("stderr"))
 	}()
 
 	wg.Wait()
@@ -129,8 +592,14 @@ func (e *Executor) ExecuteScript(ctx context.Context, projectKey, scriptName str
 		if result.Error == "" {
 			result.Error = err.Error()
 		}
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitCode(exitErr)
+		}
 	} else {
 		result.Success = true
+		result.ExitCode = 0
 	}
 
 	result.Output = outputBuilder.String()
@@ -138,7 +607,63 @@ func (e *Executor) ExecuteScript(ctx context.Context, projectKey, scriptName str
 		result.Error = errorBuilder.String()
 	}
 
-	return result, nil
+	return result
+}
+
+// runCombined runs cmd with stdout and stderr sharing one buffer. Because both
+// streams use the same writer, the command writes to a single pipe and the
+// output keeps the order in which it was emitted.
+func runCombined(cmd *exec.Cmd, result *ExecutionResult, onLine lineSink) *ExecutionResult {
+	var output bytes.Buffer
+	var writer io.Writer = &output
+	lines := &lineWriter{onLine: onLine.stream("combined")}
+	if onLine != nil {
+		writer = io.MultiWriter(&output, lines)
+	}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	err := cmd.Run()
+	lines.Flush()
+	result.Output = output.String()
+
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitCode(exitErr)
+		}
+	} else {
+		result.Success = true
+		result.ExitCode = 0
+	}
+
+	return result
+}
+
+// runInteractive runs cmd attached to duck's terminal so that it can prompt for
+// input. Nothing is captured; only the outcome is recorded in result.
+func runInteractive(cmd *exec.Cmd, result *ExecutionResult) *ExecutionResult {
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		result.Success = false
+		result.Error = err.Error()
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitCode(exitErr)
+		}
+	} else {
+		result.Success = true
+		result.ExitCode = 0
+	}
+
+	return result
 }
 
 func (e *Executor) ExecuteScriptOnProjects(ctx context.Context, projectKeys []string, scriptName string) ([]*ExecutionResult, error) {
@@ -165,12 +690,69 @@ func (e *Executor) ExecuteScriptOnProjects(ctx context.Context, projectKeys []st
 	return results, nil
 }
 
+// ExecuteScriptsParallel runs scriptName on levels of projects, such as the ones
+// of resolver.ResolveExecutionLevels. The projects of a level run concurrently,
+// at most maxConcurrency at a time (no limit when it is not positive), and a
+// level only starts once the previous one has completed. Failures do not stop
+// the run; results are returned level by level, in the order of the keys.
+//
+// When ctx is cancelled no more projects are started, and the results of the
+// projects that ran are returned with ctx's error. Options.OnEvent must be safe
+// for concurrent use, and OutputInteractive should not be combined with it.
+func (e *Executor) ExecuteScriptsParallel(ctx context.Context, levels [][]string, scriptName string, maxConcurrency int) ([]*ExecutionResult, error) {
+	var results []*ExecutionResult
+
+	for _, level := range levels {
+		limit := maxConcurrency
+		if limit <= 0 || limit > len(level) {
+			limit = len(level)
+		}
+		semaphore := make(chan struct{}, limit)
+
+		levelResults := make([]*ExecutionResult, len(level))
+		errs := make([]error, len(level))
+		var wg sync.WaitGroup
+
+	start:
+		for i, projectKey := range level {
+			select {
+			case <-ctx.Done():
+				break start
+			case semaphore <- struct{}{}:
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				levelResults[i], errs[i] = e.ExecuteScript(ctx, projectKey, scriptName)
+			}()
+		}
+		wg.Wait()
+
+		for _, result := range levelResults {
+			if result != nil {
+				results = append(results, result)
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return results, err
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
 func (e *Executor) replaceVariables(command string, project *config.AppProject, workingDir string) string {
 	replacements := map[string]string{
-		"{projectRoot}": project.Path,
-		"{projectName}": project.Config.Name,
-		"{namespace}":   project.Config.Namespace,
-		"{workingDir}":  workingDir,
+		"{projectRoot}":   project.Path,
+		"{projectName}":   project.Config.Name,
+		"{namespace}":     project.Config.Namespace,
+		"{workingDir}":    workingDir,
+		"{workspaceRoot}": e.options.WorkspaceRoot,
 	}
 
 	result := command
@@ -181,9 +763,67 @@ func (e *Executor) replaceVariables(command string, project *config.AppProject,
 	return result
 }
 
-func copyOutput(reader io.Reader, writer io.Writer) {
+func copyOutput(reader io.Reader, writer io.Writer, onLine func(string)) {
 	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
 		fmt.Fprintln(writer, scanner.Text())
+		if onLine != nil {
+			onLine(scanner.Text())
+		}
+	}
+}
+
+// lineSink receives the lines a command writes to a stream
+type lineSink func(stream, line string)
+
+// outputSink returns the sink reporting output of projectKey as EventOutput, or
+// nil without Options.OnEvent
+func (e *Executor) outputSink(projectKey, scriptName string) lineSink {
+	if e.options.OnEvent == nil {
+		return nil
+	}
+	return func(stream, line string) {
+		e.emit(Event{Type: EventOutput, ProjectKey: projectKey, Script: scriptName, Stream: stream, Line: line})
+	}
+}
+
+// stream returns a function passing the lines of one stream to sink, or nil if sink is nil
+func (sink lineSink) stream(name string) func(string) {
+	if sink == nil {
+		return nil
+	}
+	return func(line string) { sink(name, line) }
+}
+
+func (e *Executor) emit(event Event) {
+	if e.options.OnEvent != nil {
+		e.options.OnEvent(event)
+	}
+}
+
+// lineWriter calls onLine with every complete line written to it
+type lineWriter struct {
+	onLine  func(string)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.onLine(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush reports a last line that did not end in a newline
+func (w *lineWriter) Flush() {
+	if len(w.partial) > 0 && w.onLine != nil {
+		w.onLine(string(w.partial))
+		w.partial = nil
 	}
 }
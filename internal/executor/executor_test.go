@@ -0,0 +1,253 @@
+package executor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"duck/internal/config"
+)
+
+// newTestExecutor returns an executor for one project, "app", in dir
+func newTestExecutor(dir string, scripts map[string]config.Script, app *config.AppConfig, options Options) *Executor {
+	projectConfig := &config.ProjectConfig{Scripts: scripts}
+	projects := map[string]*config.AppProject{
+		"app": {Config: app, Path: dir},
+	}
+	return NewWithOptions(projectConfig, projects, options)
+}
+
+func TestResolveEnvironmentPrecedence(t *testing.T) {
+	// Each variable is set by every layer up to the one expected to win
+	for _, key := range []string{"HOST_VAR", "DUCK_VAR", "PROJECT_VAR", "SCRIPT_VAR", "OVERRIDE_VAR", "OPTION_VAR"} {
+		t.Setenv(key, "host")
+	}
+
+	e := newTestExecutor(t.TempDir(), map[string]config.Script{
+		"build": {
+			Command: "true",
+			Environment: map[string]string{
+				"SCRIPT_VAR":   "script",
+				"OVERRIDE_VAR": "script",
+				"OPTION_VAR":   "script",
+			},
+		},
+	}, &config.AppConfig{
+		Name: "app",
+		Environment: map[string]string{
+			"PROJECT_VAR":  "project",
+			"SCRIPT_VAR":   "project",
+			"OVERRIDE_VAR": "project",
+			"OPTION_VAR":   "project",
+		},
+		Scripts: map[string]config.ProjectScript{
+			"build": {Enabled: true, Environment: map[string]string{"OVERRIDE_VAR": "override"}},
+		},
+	}, Options{
+		Environment: map[string]string{"OPTION_VAR": "option"},
+	})
+	e.projectConfig.Environment = map[string]string{
+		"DUCK_VAR":     "duck",
+		"PROJECT_VAR":  "duck",
+		"SCRIPT_VAR":   "duck",
+		"OVERRIDE_VAR": "duck",
+		"OPTION_VAR":   "duck",
+	}
+
+	_, _, env, err := e.ResolveCommand("app", "build")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"HOST_VAR":     "host",
+		"DUCK_VAR":     "duck",
+		"PROJECT_VAR":  "project",
+		"SCRIPT_VAR":   "script",
+		"OVERRIDE_VAR": "override",
+		"OPTION_VAR":   "option",
+	}
+	for key, value := range want {
+		if got, _ := lookupEnv(env, key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+// lookupEnv returns the value of key in the KEY=value entries of env
+func lookupEnv(env []string, key string) (string, bool) {
+	for _, entry := range env {
+		if name, value, _ := strings.Cut(entry, "="); name == key {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func TestResolveEnvironmentIsolated(t *testing.T) {
+	t.Setenv("KEPT_VAR", "host")
+	t.Setenv("DROPPED_VAR", "host")
+
+	e := newTestExecutor(t.TempDir(), map[string]config.Script{
+		"build": {Command: "true", IsolateEnv: true},
+	}, &config.AppConfig{Name: "app"}, Options{EnvAllowlist: []string{"KEPT_VAR"}})
+
+	_, _, env, err := e.ResolveCommand("app", "build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept, _ := lookupEnv(env, "KEPT_VAR"); kept != "host" {
+		t.Errorf("KEPT_VAR = %q, want the allowlisted host value", kept)
+	}
+	if _, exists := lookupEnv(env, "DROPPED_VAR"); exists {
+		t.Errorf("DROPPED_VAR is set, want it dropped by isolateEnv")
+	}
+}
+
+func TestEnvironSorted(t *testing.T) {
+	got := Environ(map[string]string{"B": "2", "A": "1", "C": "3=3"})
+	want := []string{"A=1", "B=2", "C=3=3"}
+	if len(got) != len(want) {
+		t.Fatalf("Environ() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Environ()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestScriptSettingsPrecedence(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+
+	script := config.Script{Command: "true", Timeout: time.Minute, Retries: 1}
+	projectSettings := map[string]config.ScriptSettings{
+		"test": {Timeout: 5 * time.Minute, Retries: intPtr(3)},
+	}
+
+	tests := []struct {
+		name        string
+		script      config.Script
+		settings    map[string]config.ScriptSettings
+		options     Options
+		wantTimeout time.Duration
+		wantRetries int
+	}{
+		{
+			name:   "default",
+			script: config.Script{Command: "true"},
+		},
+		{
+			name:        "script",
+			script:      script,
+			wantTimeout: time.Minute,
+			wantRetries: 1,
+		},
+		{
+			name:        "project over script",
+			script:      script,
+			settings:    projectSettings,
+			wantTimeout: 5 * time.Minute,
+			wantRetries: 3,
+		},
+		{
+			name:        "project retries of zero disable the script's retries",
+			script:      script,
+			settings:    map[string]config.ScriptSettings{"test": {Retries: intPtr(0)}},
+			wantTimeout: time.Minute,
+			wantRetries: 0,
+		},
+		{
+			name:        "CLI over project",
+			script:      script,
+			settings:    projectSettings,
+			options:     Options{Timeout: 10 * time.Second, Retries: intPtr(0)},
+			wantTimeout: 10 * time.Second,
+			wantRetries: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestExecutor(t.TempDir(), map[string]config.Script{"test": tt.script},
+				&config.AppConfig{Name: "app", ScriptSettings: tt.settings}, tt.options)
+
+			timeout, retries := e.scriptSettings(tt.script, e.projects["app"], "test")
+			if timeout != tt.wantTimeout {
+				t.Errorf("timeout = %v, want %v", timeout, tt.wantTimeout)
+			}
+			if retries != tt.wantRetries {
+				t.Errorf("retries = %d, want %d", retries, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestResolveCommandExpandsWorkspaceRoot(t *testing.T) {
+	workspace := t.TempDir()
+	projectDir := filepath.Join(workspace, "apps", "api")
+
+	e := newTestExecutor(projectDir, map[string]config.Script{
+		"lint": {
+			Command:    "{workspaceRoot}/bin/tool --root {projectRoot}",
+			WorkingDir: "{workspaceRoot}/tools",
+		},
+		"release": {
+			Commands: []string{
+				"{workspaceRoot}/bin/tool build {projectName}",
+				"{workspaceRoot}/bin/tool publish --from {workingDir}",
+			},
+		},
+	}, &config.AppConfig{Name: "api"}, Options{WorkspaceRoot: workspace})
+
+	command, workingDir, _, err := e.ResolveCommand("app", "lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := workspace + "/bin/tool --root " + projectDir; command != want {
+		t.Errorf("command = %q, want %q", command, want)
+	}
+	if want := workspace + "/tools"; workingDir != want {
+		t.Errorf("workingDir = %q, want %q", workingDir, want)
+	}
+
+	command, workingDir, _, err = e.ResolveCommand("app", "release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workingDir != projectDir {
+		t.Errorf("workingDir = %q, want the project root %q", workingDir, projectDir)
+	}
+	want := workspace + "/bin/tool build api && " + workspace + "/bin/tool publish --from " + projectDir
+	if command != want {
+		t.Errorf("command = %q, want %q", command, want)
+	}
+}
+
+func TestOverriddenScriptIsValidated(t *testing.T) {
+	scripts := map[string]config.Script{
+		"serve": {Command: "./server", ReadyWhen: "^listening on"},
+	}
+
+	e := newTestExecutor(t.TempDir(), scripts, &config.AppConfig{
+		Name:    "api",
+		Scripts: map[string]config.ProjectScript{"serve": {Enabled: true, Command: "  "}},
+	}, Options{})
+	_, _, _, err := e.ResolveCommand("app", "serve")
+	if err == nil || !strings.Contains(err.Error(), "script serve as overridden by project app: command must not be blank") {
+		t.Fatalf("err = %v, want the overridden script to be rejected", err)
+	}
+
+	e = newTestExecutor(t.TempDir(), scripts, &config.AppConfig{
+		Name:    "api",
+		Scripts: map[string]config.ProjectScript{"serve": {Enabled: true, Command: "./server --port 9090"}},
+	}, Options{})
+	command, _, _, err := e.ResolveCommand("app", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if command != "./server --port 9090" {
+		t.Errorf("command = %q, want the project's command", command)
+	}
+}
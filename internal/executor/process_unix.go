@@ -0,0 +1,46 @@
+//go:build !windows
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// defaultShell runs script commands when neither the workspace nor the script sets a shell
+var defaultShell = []string{"sh", "-c"}
+
+// killProcessGroup runs cmd in its own process group and, when its context is
+// done, kills the whole group so that child processes holding the output pipes
+// are stopped too
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// detachProcessGroup runs cmd in its own process group, out of reach of the
+// Ctrl-C sent to duck's, so that a service only stops when duck stops it
+func detachProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalProcessGroup sends SIGTERM, or SIGKILL when kill is set, to the
+// process group of a command started with detachProcessGroup
+func signalProcessGroup(cmd *exec.Cmd, kill bool) error {
+	signal := syscall.SIGTERM
+	if kill {
+		signal = syscall.SIGKILL
+	}
+	return syscall.Kill(-cmd.Process.Pid, signal)
+}
+
+// exitCode returns the exit code of a finished command, reporting a command
+// killed by signal n as 128+n like the shell does
+func exitCode(exitErr *exec.ExitError) int {
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package executor
+
+import "os/exec"
+
+// defaultShell runs script commands when neither the workspace nor the script sets a shell
+var defaultShell = []string{"cmd", "/c"}
+
+// killProcessGroup is a no-op on Windows, where only the shell itself is killed
+func killProcessGroup(cmd *exec.Cmd) {}
+
+// detachProcessGroup is a no-op on Windows
+func detachProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup kills the process of cmd; Windows has no SIGTERM
+func signalProcessGroup(cmd *exec.Cmd, kill bool) error {
+	return cmd.Process.Kill()
+}
+
+// exitCode returns the exit code of a finished command
+func exitCode(exitErr *exec.ExitError) int {
+	return exitErr.ExitCode()
+}
@@ -0,0 +1,51 @@
+//go:build !windows
+
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"duck/internal/config"
+)
+
+// flakyCommand fails with exit code 75 on its first run in a directory and
+// succeeds afterwards
+const flakyCommand = "if [ -f attempted ]; then exit 0; fi; touch attempted; exit 75"
+
+func TestExecuteScriptRetriesListedExitCode(t *testing.T) {
+	e := newTestExecutor(t.TempDir(), map[string]config.Script{
+		"test": {Command: flakyCommand, Retries: 2, RetryOn: []int{75}},
+	}, &config.AppConfig{Name: "app"}, Options{})
+
+	result, err := e.ExecuteScript(context.Background(), "app", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success {
+		t.Fatalf("Success = false (exit %d), want the retry to succeed", result.ExitCode)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("Attempts = %d, want 2", result.Attempts)
+	}
+}
+
+func TestExecuteScriptDoesNotRetryOtherExitCodes(t *testing.T) {
+	e := newTestExecutor(t.TempDir(), map[string]config.Script{
+		"test": {Command: flakyCommand, Retries: 2, RetryOn: []int{1}},
+	}, &config.AppConfig{Name: "app"}, Options{})
+
+	result, err := e.ExecuteScript(context.Background(), "app", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success {
+		t.Fatal("Success = true, want the first failure to stand")
+	}
+	if result.ExitCode != 75 {
+		t.Errorf("ExitCode = %d, want 75", result.ExitCode)
+	}
+	if result.Attempts != 1 {
+		t.Errorf("Attempts = %d, want 1: exit code 75 is not in retryOn", result.Attempts)
+	}
+}
@@ -0,0 +1,258 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ServicesDir is the directory, relative to the workspace root, where the
+// output of services is logged
+var ServicesDir = filepath.Join(".duck", "services")
+
+// readyPollInterval is how often the log of a starting service is read
+const readyPollInterval = 50 * time.Millisecond
+
+// serviceStopTimeout is how long a service may take to exit after SIGTERM
+// before it is killed
+const serviceStopTimeout = 5 * time.Second
+
+// Service is a script with readyWhen left running in the background
+type Service struct {
+	ProjectKey string
+	Script     string
+	PID        int
+	// LogFile receives the service's stdout and stderr, also after duck exits
+	LogFile string
+
+	cmd     *exec.Cmd
+	exited  chan struct{} // Closed once the process has exited
+	waitErr error
+}
+
+// startService starts command in the background, logging its output to a file,
+// and waits for a line of it to match readyWhen. Once one does, result succeeds
+// and the service is left running until StopServices. If the service exits
+// first, takes longer than timeout (when positive) or ctx is done, result fails
+// and the service is stopped. A service of the same project and script that is
+// already running is stopped first, so that running the script again restarts it.
+func (e *Executor) startService(ctx context.Context, result *ExecutionResult, shell []string, command, workingDir string, env []string, readyWhen string, timeout time.Duration, onLine lineSink) {
+	result.Attempts = 1
+	result.ExitCode = -1
+
+	ready, err := regexp.Compile(readyWhen)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid readyWhen: %v", err)
+		return
+	}
+
+	if err := e.stopService(result.ProjectKey, result.Script); err != nil {
+		result.Error = err.Error()
+		return
+	}
+
+	logFile := filepath.Join(e.options.WorkspaceRoot, ServicesDir, serviceLogName(result.ProjectKey, result.Script))
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		result.Error = fmt.Sprintf("failed to create services directory: %v", err)
+		return
+	}
+	log, err := os.Create(logFile)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to create service log: %v", err)
+		return
+	}
+
+	// The service writes to the log file rather than to a pipe, so that it
+	// keeps running when duck exits without stopping it
+	args := append(append([]string(nil), shell[1:]...), command)
+	cmd := exec.Command(shell[0], args...)
+	cmd.Dir = workingDir
+	cmd.Env = env
+	cmd.Stdout = log
+	cmd.Stderr = log
+	detachProcessGroup(cmd)
+
+	err = cmd.Start()
+	log.Close()
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to start command: %v", err)
+		return
+	}
+
+	service := &Service{
+		ProjectKey: result.ProjectKey,
+		Script:     result.Script,
+		PID:        cmd.Process.Pid,
+		LogFile:    logFile,
+		cmd:        cmd,
+		exited:     make(chan struct{}),
+	}
+	go func() {
+		service.waitErr = cmd.Wait()
+		close(service.exited)
+	}()
+
+	tail, err := os.Open(logFile)
+	if err != nil {
+		service.stop()
+		result.Error = fmt.Sprintf("failed to read service log: %v", err)
+		return
+	}
+	defer tail.Close()
+
+	var output strings.Builder
+	var partial string
+	reader := bufio.NewReader(tail)
+	// readLines consumes the complete lines logged so far and reports whether
+	// one of them matched readyWhen
+	readLines := func() bool {
+		for {
+			chunk, err := reader.ReadString('\n')
+			partial += chunk
+			if err != nil {
+				return false
+			}
+
+			line := strings.TrimRight(partial, "\r\n")
+			partial = ""
+			output.WriteString(line + "\n")
+			if onLine != nil {
+				onLine("combined", line)
+			}
+			if ready.MatchString(line) {
+				return true
+			}
+		}
+	}
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if readLines() {
+			result.Success = true
+			result.ExitCode = 0
+			result.Output = output.String()
+			result.Service = service
+
+			e.servicesMu.Lock()
+			e.services = append(e.services, service)
+			e.servicesMu.Unlock()
+			return
+		}
+
+		select {
+		case <-service.exited:
+			readLines()
+			result.Output = output.String() + partial
+			result.Error = fmt.Sprintf("service exited before its output matched readyWhen %q", readyWhen)
+
+			var exitErr *exec.ExitError
+			if errors.As(service.waitErr, &exitErr) {
+				result.ExitCode = exitCode(exitErr)
+			} else if service.waitErr == nil {
+				result.ExitCode = 0
+			}
+			return
+		case <-deadline:
+			service.stop()
+			result.Output = output.String()
+			result.Error = fmt.Sprintf("timed out after %v waiting for output matching readyWhen %q", timeout, readyWhen)
+			return
+		case <-ctx.Done():
+			service.stop()
+			result.Output = output.String()
+			result.Error = ctx.Err().Error()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Services returns the services started by ExecuteScript that have not been
+// stopped, in the order they became ready
+func (e *Executor) Services() []*Service {
+	e.servicesMu.Lock()
+	defer e.servicesMu.Unlock()
+	return append([]*Service(nil), e.services...)
+}
+
+// StopServices stops the services started by ExecuteScript, the last one to
+// become ready first so that services outlive the ones depending on them
+func (e *Executor) StopServices() error {
+	e.servicesMu.Lock()
+	services := e.services
+	e.services = nil
+	e.servicesMu.Unlock()
+
+	var errs []error
+	for i := len(services) - 1; i >= 0; i-- {
+		if err := services[i].stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopService stops the running service of projectKey's scriptName, if any
+func (e *Executor) stopService(projectKey, scriptName string) error {
+	e.servicesMu.Lock()
+	var service *Service
+	for i, candidate := range e.services {
+		if candidate.ProjectKey == projectKey && candidate.Script == scriptName {
+			service = candidate
+			e.services = append(e.services[:i], e.services[i+1:]...)
+			break
+		}
+	}
+	e.servicesMu.Unlock()
+
+	if service == nil {
+		return nil
+	}
+	return service.stop()
+}
+
+// stop asks the service's process group to terminate, and kills it if it is
+// still running serviceStopTimeout later
+func (s *Service) stop() error {
+	select {
+	case <-s.exited:
+		return nil
+	default:
+	}
+
+	if err := signalProcessGroup(s.cmd, false); err != nil {
+		return fmt.Errorf("failed to stop service %s of %s (pid %d): %w", s.Script, s.ProjectKey, s.PID, err)
+	}
+
+	select {
+	case <-s.exited:
+	case <-time.After(serviceStopTimeout):
+		if err := signalProcessGroup(s.cmd, true); err != nil {
+			return fmt.Errorf("failed to kill service %s of %s (pid %d): %w", s.Script, s.ProjectKey, s.PID, err)
+		}
+		<-s.exited
+	}
+	return nil
+}
+
+// serviceLogName is the name of the log file of projectKey's scriptName in
+// ServicesDir; project keys may contain path separators
+func serviceLogName(projectKey, scriptName string) string {
+	name := strings.NewReplacer("/", "_", `\`, "_", ":", "_").Replace(projectKey + "." + scriptName)
+	return name + ".log"
+}
@@ -0,0 +1,146 @@
+//go:build !windows
+
+package executor
+
+import (
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"duck/internal/config"
+)
+
+// serviceCommand logs a line, becomes ready and then keeps running
+const serviceCommand = "echo starting; echo listening on 8080; exec sleep 30"
+
+// newServiceExecutor runs the scripts on a project "app" in a temporary
+// workspace, stopping the services it leaves behind when the test ends
+func newServiceExecutor(t *testing.T, scripts map[string]config.Script) *Executor {
+	t.Helper()
+	dir := t.TempDir()
+	e := newTestExecutor(dir, scripts, &config.AppConfig{Name: "app"}, Options{WorkspaceRoot: dir})
+	t.Cleanup(func() { e.StopServices() })
+	return e
+}
+
+// running reports whether the process pid exists
+func running(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
+
+func TestServiceRunsInBackgroundUntilStopped(t *testing.T) {
+	e := newServiceExecutor(t, map[string]config.Script{
+		"serve": {Command: serviceCommand, ReadyWhen: "^listening on"},
+	})
+
+	result, err := e.ExecuteScript(context.Background(), "app", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success {
+		t.Fatalf("Success = false: %s", result.Error)
+	}
+	if result.Service == nil {
+		t.Fatal("Service = nil, want the running service")
+	}
+	if result.Output != "starting\nlistening on 8080\n" {
+		t.Errorf("Output = %q, want the lines up to the ready one", result.Output)
+	}
+
+	pid := result.Service.PID
+	if !running(pid) {
+		t.Fatalf("service (pid %d) is not running after it became ready", pid)
+	}
+	if services := e.Services(); len(services) != 1 || services[0] != result.Service {
+		t.Errorf("Services() = %v, want the started service", services)
+	}
+
+	if err := e.StopServices(); err != nil {
+		t.Fatal(err)
+	}
+	if running(pid) {
+		t.Errorf("service (pid %d) still running after StopServices", pid)
+	}
+	if services := e.Services(); len(services) != 0 {
+		t.Errorf("Services() = %v after StopServices, want none", services)
+	}
+
+	log, err := os.ReadFile(result.Service.LogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(log), "listening on 8080") {
+		t.Errorf("log file = %q, want the service's output", log)
+	}
+}
+
+func TestServiceExitingBeforeReadyFails(t *testing.T) {
+	e := newServiceExecutor(t, map[string]config.Script{
+		"serve": {Command: "echo address in use; exit 3", ReadyWhen: "^listening on"},
+	})
+
+	result, err := e.ExecuteScript(context.Background(), "app", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success {
+		t.Fatal("Success = true, want a service that exited to fail")
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", result.ExitCode)
+	}
+	if !strings.Contains(result.Output, "address in use") {
+		t.Errorf("Output = %q, want the service's output", result.Output)
+	}
+	if result.Service != nil || len(e.Services()) != 0 {
+		t.Error("a service that exited is still tracked")
+	}
+}
+
+func TestServiceNotReadyWithinTimeoutIsStopped(t *testing.T) {
+	e := newServiceExecutor(t, map[string]config.Script{
+		"serve": {Command: "exec sleep 30", ReadyWhen: "^listening on", Timeout: 200 * time.Millisecond},
+	})
+
+	result, err := e.ExecuteScript(context.Background(), "app", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success {
+		t.Fatal("Success = true, want the service to time out")
+	}
+	if !strings.Contains(result.Error, "timed out after 200ms") {
+		t.Errorf("Error = %q, want a timeout", result.Error)
+	}
+	if len(e.Services()) != 0 {
+		t.Error("a service that timed out is still tracked")
+	}
+}
+
+func TestServiceRestartsWhenRunAgain(t *testing.T) {
+	e := newServiceExecutor(t, map[string]config.Script{
+		"serve": {Command: serviceCommand, ReadyWhen: "^listening on"},
+	})
+
+	first, err := e.ExecuteScript(context.Background(), "app", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := e.ExecuteScript(context.Background(), "app", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Success || !second.Success {
+		t.Fatalf("runs failed: %q, %q", first.Error, second.Error)
+	}
+
+	if running(first.Service.PID) {
+		t.Errorf("first service (pid %d) still running after the script ran again", first.Service.PID)
+	}
+	if services := e.Services(); len(services) != 1 || services[0] != second.Service {
+		t.Errorf("Services() = %v, want only the restarted service", services)
+	}
+}
@@ -0,0 +1,167 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+)
+
+// Project holds the values a filter expression can refer to:
+//
+//	name       string        the project name
+//	ns         string        the project namespace
+//	tags       list(string)  the project tags
+//	deps       list(string)  keys of the projects it depends on
+//	dependents list(string)  keys of the projects that depend on it directly
+//
+// "namespace" is a reserved word in CEL, so the namespace is declared as ns;
+// Compile rewrites a bare namespace to ns, so either name works. All values
+// are also available as fields of project, e.g. project.namespace.
+// tag(x) is shorthand for x in tags, so expressions such as
+//
+//	namespace == "backend" && tag("worker") && !tag("deprecated")
+//
+// read like the command line flags they replace.
+type Project struct {
+	Name       string
+	Namespace  string
+	Tags       []string
+	Deps       []string
+	Dependents []string
+}
+
+// Filter is a compiled CEL expression that selects projects
+type Filter struct {
+	expression string
+	program    cel.Program
+}
+
+// Compile parses and type-checks expression, which must evaluate to a bool
+func Compile(expression string) (*Filter, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("name", cel.StringType),
+		cel.Variable("ns", cel.StringType),
+		cel.Variable("tags", cel.ListType(cel.StringType)),
+		cel.Variable("deps", cel.ListType(cel.StringType)),
+		cel.Variable("dependents", cel.ListType(cel.StringType)),
+		cel.Variable("project", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Macros(cel.GlobalMacro("tag", 1, expandTag)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create filter environment: %w", err)
+	}
+
+	ast, issues := env.Compile(rewriteNamespace(expression))
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid filter '%s': %w", expression, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("invalid filter '%s': must evaluate to a bool, got %s", expression, ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter '%s': %w", expression, err)
+	}
+
+	return &Filter{expression: expression, program: program}, nil
+}
+
+// Match evaluates the filter against project
+func (f *Filter) Match(project Project) (bool, error) {
+	fields := map[string]interface{}{
+		"name":       project.Name,
+		"namespace":  project.Namespace,
+		"tags":       nonNil(project.Tags),
+		"deps":       nonNil(project.Deps),
+		"dependents": nonNil(project.Dependents),
+	}
+
+	out, _, err := f.program.Eval(map[string]interface{}{
+		"name":       fields["name"],
+		"ns":         fields["namespace"],
+		"tags":       fields["tags"],
+		"deps":       fields["deps"],
+		"dependents": fields["dependents"],
+		"project":    fields,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate filter '%s' for %s: %w", f.expression, project.Name, err)
+	}
+
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("filter '%s' did not evaluate to a bool", f.expression)
+	}
+	return matched, nil
+}
+
+// expandTag rewrites tag(x) to x in tags
+func expandTag(eh cel.MacroExprFactory, target ast.Expr, args []ast.Expr) (ast.Expr, *cel.Error) {
+	return eh.NewCall(operators.In, args[0], eh.NewIdent("tags")), nil
+}
+
+// rewriteNamespace replaces the identifier namespace, which CEL reserves, with
+// ns. String literals and field selections such as project.namespace are kept.
+func rewriteNamespace(expression string) string {
+	var out strings.Builder
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := stringEnd(expression, i)
+			out.WriteString(expression[i:end])
+			i = end
+		case isIdentStart(c):
+			end := i + 1
+			for end < len(expression) && (isIdentStart(expression[end]) || expression[end] >= '0' && expression[end] <= '9') {
+				end++
+			}
+			word := expression[i:end]
+			if word == "namespace" && !strings.HasSuffix(strings.TrimRight(expression[:i], " \t\r\n"), ".") {
+				word = "ns"
+			}
+			out.WriteString(word)
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// stringEnd returns the index just past the string literal starting at start,
+// which may be triple-quoted, or len(expression) when it is not terminated
+func stringEnd(expression string, start int) int {
+	quote := expression[start : start+1]
+	if strings.HasPrefix(expression[start:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	for i := start + len(quote); i < len(expression); i++ {
+		if expression[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(expression[i:], quote) {
+			return i + len(quote)
+		}
+	}
+	return len(expression)
+}
+
+// isIdentStart reports whether c can start a CEL identifier
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// nonNil avoids passing nil lists, which CEL treats as null rather than empty
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,81 @@
+package filter
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	worker := Project{
+		Name:      "queue-worker",
+		Namespace: "backend",
+		Tags:      []string{"go", "worker"},
+		Deps:      []string{"shared/database", "shared/logging"},
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		want       bool
+	}{
+		{"name", `name == "queue-worker"`, true},
+		{"name mismatch", `name != "queue-worker"`, false},
+		{"namespace", `namespace == "backend"`, true},
+		{"namespace mismatch", `namespace == "frontend"`, false},
+		{"ns", `ns == "backend"`, true},
+		{"project field", `project.namespace == "backend"`, true},
+		{"namespace in a string", `name == "namespace" || namespace == "backend"`, true},
+		{"tag", `tag("worker")`, true},
+		{"missing tag", `tag("deprecated")`, false},
+		{"negated tag", `!tag("deprecated")`, true},
+		{"combined", `namespace == "backend" && tag("worker") && !tag("deprecated")`, true},
+		{"or", `tag("node") || (namespace == "backend" && size(deps) > 1)`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := Compile(tt.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := filter.Match(worker)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Match(%s) = %v, want %v", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileRejectsInvalidExpressions(t *testing.T) {
+	for _, expression := range []string{
+		`namespace ==`,
+		`name`,
+		`tag("a", "b")`,
+		`owner == "me"`,
+	} {
+		if _, err := Compile(expression); err == nil {
+			t.Errorf("Compile(%s) succeeded, want an error", expression)
+		}
+	}
+}
+
+func TestRewriteNamespace(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{`namespace == "backend"`, `ns == "backend"`},
+		{`project.namespace == "backend"`, `project.namespace == "backend"`},
+		{`project. namespace == "backend"`, `project. namespace == "backend"`},
+		{`name == "namespace"`, `name == "namespace"`},
+		{`name == 'a\'namespace'`, `name == 'a\'namespace'`},
+		{`name == """namespace""" && namespace != ""`, `name == """namespace""" && ns != ""`},
+		{`namespaces == 1 || my_namespace == 2`, `namespaces == 1 || my_namespace == 2`},
+	}
+
+	for _, tt := range tests {
+		if got := rewriteNamespace(tt.expression); got != tt.want {
+			t.Errorf("rewriteNamespace(%s) = %s, want %s", tt.expression, got, tt.want)
+		}
+	}
+}
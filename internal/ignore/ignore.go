@@ -0,0 +1,167 @@
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the ignore file read from the workspace root
+const FileName = ".duckignore"
+
+// DefaultPatterns are directories that are skipped unless default ignores are disabled
+var DefaultPatterns = []string{".git", "node_modules", "vendor", "dist"}
+
+// Matcher decides whether a path should be skipped while walking the workspace.
+// Patterns follow a small subset of gitignore syntax:
+//   - blank lines and lines starting with '#' are ignored
+//   - a pattern without a slash matches a file or directory name at any depth
+//   - a pattern containing a slash is matched against the path relative to the root
+//   - a trailing '/' only matches directories, a leading '!' re-includes a path
+//   - a leading "**/" matches at any depth
+type Matcher struct {
+	root     string
+	patterns []pattern
+}
+
+type pattern struct {
+	glob     string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Load builds a Matcher for root from the optional .duckignore file in root,
+// preceded by DefaultPatterns when useDefaults is set
+func Load(root string, useDefaults bool) (*Matcher, error) {
+	var lines []string
+	if useDefaults {
+		lines = append(lines, DefaultPatterns...)
+	}
+
+	path := filepath.Join(root, FileName)
+	file, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", FileName, err)
+	}
+	if err == nil {
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", FileName, err)
+		}
+	}
+
+	return New(root, lines), nil
+}
+
+// New builds a Matcher for root from pattern lines
+func New(root string, lines []string) *Matcher {
+	m := &Matcher{root: root}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := pattern{}
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		line = strings.TrimPrefix(line, "**/")
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		p.glob = line
+		m.patterns = append(m.patterns, p)
+	}
+
+	return m
+}
+
+// Match reports whether path should be ignored. The last matching pattern wins.
+// A nil Matcher ignores nothing.
+func (m *Matcher) Match(path string, isDir bool) bool {
+	if m == nil || len(m.patterns) == 0 {
+		return false
+	}
+
+	rel := path
+	if filepath.IsAbs(path) {
+		if r, err := filepath.Rel(m.root, path); err == nil {
+			rel = r
+		}
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		return false
+	}
+	outside := strings.HasPrefix(rel, "../")
+	name := filepath.Base(path)
+
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		var matched bool
+		if p.anchored {
+			matched = !outside && matchGlob(p.glob, rel)
+		} else {
+			matched = matchGlob(p.glob, name)
+		}
+
+		if matched {
+			ignored = !p.negate
+		}
+	}
+
+	return ignored
+}
+
+// String returns the normalized patterns, one per line
+func (m *Matcher) String() string {
+	if m == nil {
+		return ""
+	}
+
+	lines := make([]string, 0, len(m.patterns))
+	for _, p := range m.patterns {
+		line := p.glob
+		if p.anchored {
+			line = "/" + line
+		}
+		if p.dirOnly {
+			line += "/"
+		}
+		if p.negate {
+			line = "!" + line
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func matchGlob(glob, name string) bool {
+	matched, err := filepath.Match(filepath.FromSlash(glob), filepath.FromSlash(name))
+	return err == nil && matched
+}
@@ -20,32 +20,49 @@ func New(projects map[string]*config.AppProject) *DependencyResolver {
 type ResolutionResult struct {
 	ExecutionOrder []string
 	Dependencies   map[string][]string
+	// Cyclic holds the sorted keys of projects that are part of, or depend on,
+	// a cycle. It is only set by ResolveBestEffortOrder.
+	Cyclic []string
 }
 
 func (r *DependencyResolver) ResolveExecutionOrder() (*ResolutionResult, error) {
-	result := &ResolutionResult{
-		Dependencies: make(map[string][]string),
+	result, cyclic, err := r.resolveOrder()
+	if err != nil {
+		return nil, err
 	}
 
-	graph := make(map[string][]string)
-	inDegree := make(map[string]int)
+	if len(cyclic) > 0 {
+		return nil, fmt.Errorf("circular dependency detected")
+	}
 
-	for key := range r.projects {
-		graph[key] = []string{}
-		inDegree[key] = 0
+	return result, nil
+}
+
+// ResolveBestEffortOrder is like ResolveExecutionOrder, but does not fail on
+// cycles. Projects that cannot be ordered because of a cycle are appended to
+// the execution order in sorted order and reported in Cyclic.
+func (r *DependencyResolver) ResolveBestEffortOrder() (*ResolutionResult, error) {
+	result, cyclic, err := r.resolveOrder()
+	if err != nil {
+		return nil, err
 	}
 
-	for key, project := range r.projects {
-		for _, dep := range project.Config.Dependencies {
-			if _, exists := r.projects[dep]; !exists {
-				return nil, fmt.Errorf("project %s depends on %s, but %s was not found", key, dep, dep)
-			}
+	result.ExecutionOrder = append(result.ExecutionOrder, cyclic...)
+	result.Cyclic = cyclic
 
-			graph[dep] = append(graph[dep], key)
-			inDegree[key]++
+	return result, nil
+}
 
-			result.Dependencies[key] = append(result.Dependencies[key], dep)
-		}
+// resolveOrder orders the projects topologically and returns the sorted keys
+// of the projects left unordered because of cycles
+func (r *DependencyResolver) resolveOrder() (*ResolutionResult, []string, error) {
+	graph, inDegree, dependencies, err := r.buildGraph()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &ResolutionResult{
+		Dependencies: dependencies,
 	}
 
 	queue := []string{}
@@ -85,13 +102,181 @@ func (r *DependencyResolver) ResolveExecutionOrder() (*ResolutionResult, error)
 		}
 	}
 
-	if len(result.ExecutionOrder) != len(r.projects) {
-		return nil, fmt.Errorf("circular dependency detected")
+	var cyclic []string
+	for key, degree := range inDegree {
+		if degree > 0 {
+			cyclic = append(cyclic, key)
+		}
+	}
+	sort.Strings(cyclic)
+
+	return result, cyclic, nil
+}
+
+// ResolveForTargets returns the execution order of targets and all of their
+// transitive dependencies, leaving out every other project
+func (r *DependencyResolver) ResolveForTargets(targets []string) (*ResolutionResult, error) {
+	subset := make(map[string]*config.AppProject)
+
+	var visit func(key string) error
+	visit = func(key string) error {
+		if _, seen := subset[key]; seen {
+			return nil
+		}
+
+		project, exists := r.projects[key]
+		if !exists {
+			return fmt.Errorf("project %s was not found", key)
+		}
+		subset[key] = project
+
+		for _, dep := range project.Config.Dependencies {
+			if _, exists := r.projects[dep]; !exists {
+				return fmt.Errorf("project %s depends on %s, but %s was not found", key, dep, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, target := range targets {
+		if err := visit(target); err != nil {
+			return nil, err
+		}
+	}
+
+	return New(subset).ResolveExecutionOrder()
+}
+
+// AffectedBy returns the projects in changed together with every project that
+// transitively depends on one of them, in execution order
+func (r *DependencyResolver) AffectedBy(changed []string) ([]string, error) {
+	affected := make(map[string]bool)
+	for _, key := range changed {
+		if _, exists := r.projects[key]; !exists {
+			return nil, fmt.Errorf("project %s was not found", key)
+		}
+		affected[key] = true
+		for _, dependent := range r.GetTransitiveDependents(key) {
+			affected[dependent] = true
+		}
+	}
+
+	result, err := r.ResolveExecutionOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	var ordered []string
+	for _, key := range result.ExecutionOrder {
+		if affected[key] {
+			ordered = append(ordered, key)
+		}
+	}
+	return ordered, nil
+}
+
+// ResolveTeardownOrder returns the reverse of the execution order: dependents
+// come before the projects they depend on
+func (r *DependencyResolver) ResolveTeardownOrder() (*ResolutionResult, error) {
+	result, err := r.ResolveExecutionOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	reverseOrder(result.ExecutionOrder)
+	return result, nil
+}
+
+// ResolveBestEffortTeardownOrder is the reverse of ResolveBestEffortOrder
+func (r *DependencyResolver) ResolveBestEffortTeardownOrder() (*ResolutionResult, error) {
+	result, err := r.ResolveBestEffortOrder()
+	if err != nil {
+		return nil, err
 	}
 
+	reverseOrder(result.ExecutionOrder)
 	return result, nil
 }
 
+func reverseOrder(order []string) {
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+}
+
+// ResolveExecutionLevels groups projects into levels where every project only
+// depends on projects in earlier levels. Keys within a level are sorted.
+func (r *DependencyResolver) ResolveExecutionLevels() ([][]string, error) {
+	graph, inDegree, _, err := r.buildGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	var current []string
+	for key, degree := range inDegree {
+		if degree == 0 {
+			current = append(current, key)
+		}
+	}
+
+	var levels [][]string
+	resolved := 0
+
+	for len(current) > 0 {
+		sort.Strings(current)
+		levels = append(levels, current)
+		resolved += len(current)
+
+		var next []string
+		for _, key := range current {
+			for _, dependent := range graph[key] {
+				inDegree[dependent]--
+				if inDegree[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		current = next
+	}
+
+	if resolved != len(r.projects) {
+		return nil, fmt.Errorf("circular dependency detected")
+	}
+
+	return levels, nil
+}
+
+// buildGraph maps each project to its dependents and counts each project's
+// dependencies, failing on dependencies that are not known projects
+func (r *DependencyResolver) buildGraph() (map[string][]string, map[string]int, map[string][]string, error) {
+	graph := make(map[string][]string)
+	inDegree := make(map[string]int)
+	dependencies := make(map[string][]string)
+
+	for key := range r.projects {
+		graph[key] = []string{}
+		inDegree[key] = 0
+	}
+
+	for key, project := range r.projects {
+		for _, dep := range project.Config.Dependencies {
+			if _, exists := r.projects[dep]; !exists {
+				return nil, nil, nil, fmt.Errorf("project %s depends on %s, but %s was not found", key, dep, dep)
+			}
+
+			graph[dep] = append(graph[dep], key)
+			inDegree[key]++
+
+			dependencies[key] = append(dependencies[key], dep)
+		}
+	}
+
+	return graph, inDegree, dependencies, nil
+}
+
 func (r *DependencyResolver) GetDependents(projectKey string) []string {
 	var dependents []string
 
@@ -108,6 +293,73 @@ func (r *DependencyResolver) GetDependents(projectKey string) []string {
 	return dependents
 }
 
+// GetTransitiveDependents returns every project that depends on projectKey directly
+// or through other projects, sorted and excluding projectKey itself
+func (r *DependencyResolver) GetTransitiveDependents(projectKey string) []string {
+	return r.GetDependentsWithin(projectKey, 0)
+}
+
+// GetTransitiveDependencies returns every project that projectKey depends on
+// directly or through other projects, sorted and excluding projectKey itself
+func (r *DependencyResolver) GetTransitiveDependencies(projectKey string) []string {
+	return r.GetDependenciesWithin(projectKey, 0)
+}
+
+// GetDependentsWithin is GetTransitiveDependents limited to projects at most
+// maxDepth hops away (0 for no limit)
+func (r *DependencyResolver) GetDependentsWithin(projectKey string, maxDepth int) []string {
+	return r.walk(projectKey, maxDepth, r.GetDependents)
+}
+
+// GetDependenciesWithin is GetTransitiveDependencies limited to projects at most
+// maxDepth hops away (0 for no limit)
+func (r *DependencyResolver) GetDependenciesWithin(projectKey string, maxDepth int) []string {
+	return r.walk(projectKey, maxDepth, r.getDependencies)
+}
+
+// getDependencies returns the known projects that projectKey depends on directly
+func (r *DependencyResolver) getDependencies(projectKey string) []string {
+	project, exists := r.projects[projectKey]
+	if !exists {
+		return nil
+	}
+
+	var dependencies []string
+	for _, dep := range project.Config.Dependencies {
+		if _, exists := r.projects[dep]; exists {
+			dependencies = append(dependencies, dep)
+		}
+	}
+	return dependencies
+}
+
+// walk visits the projects reachable from start through next breadth-first, up
+// to maxDepth hops (0 for no limit), and returns them sorted without start.
+// Each project is visited once, so cycles are safe.
+func (r *DependencyResolver) walk(start string, maxDepth int, next func(string) []string) []string {
+	visited := map[string]bool{start: true}
+	current := []string{start}
+	var reached []string
+
+	for depth := 1; len(current) > 0 && (maxDepth == 0 || depth <= maxDepth); depth++ {
+		var following []string
+		for _, key := range current {
+			for _, neighbor := range next(key) {
+				if visited[neighbor] {
+					continue
+				}
+				visited[neighbor] = true
+				reached = append(reached, neighbor)
+				following = append(following, neighbor)
+			}
+		}
+		current = following
+	}
+
+	sort.Strings(reached)
+	return reached
+}
+
 func (r *DependencyResolver) ValidateDependencies() error {
 	_, err := r.ResolveExecutionOrder()
 	return err
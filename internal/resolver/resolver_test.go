@@ -0,0 +1,126 @@
+package resolver
+
+import (
+	"slices"
+	"testing"
+
+	"duck/internal/config"
+)
+
+// projectsWithDependencies returns projects keyed by name with the given dependencies
+func projectsWithDependencies(dependencies map[string][]string) map[string]*config.AppProject {
+	projects := make(map[string]*config.AppProject, len(dependencies))
+	for key, deps := range dependencies {
+		projects[key] = &config.AppProject{Config: &config.AppConfig{Name: key, Dependencies: deps}}
+	}
+	return projects
+}
+
+func TestTeardownReversesBuildOrderOnDiamond(t *testing.T) {
+	// A depends on B and C, which both depend on D
+	r := New(projectsWithDependencies(map[string][]string{
+		"A": {"B", "C"},
+		"B": {"D"},
+		"C": {"D"},
+		"D": nil,
+	}))
+
+	build, err := r.ResolveExecutionOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"D", "B", "C", "A"}; !slices.Equal(build.ExecutionOrder, want) {
+		t.Fatalf("build order = %v, want %v", build.ExecutionOrder, want)
+	}
+
+	teardown, err := r.ResolveTeardownOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := slices.Clone(build.ExecutionOrder)
+	slices.Reverse(want)
+	if !slices.Equal(teardown.ExecutionOrder, want) {
+		t.Errorf("teardown order = %v, want the reverse of the build order %v", teardown.ExecutionOrder, want)
+	}
+}
+
+func TestTeardownOrderFailsOnCycle(t *testing.T) {
+	r := New(projectsWithDependencies(map[string][]string{
+		"A": {"B"},
+		"B": {"A"},
+	}))
+
+	if _, err := r.ResolveTeardownOrder(); err == nil {
+		t.Fatal("ResolveTeardownOrder() succeeded on a cycle, want an error")
+	}
+
+	result, err := r.ResolveBestEffortTeardownOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A", "B"}; !slices.Equal(result.Cyclic, want) {
+		t.Errorf("Cyclic = %v, want %v", result.Cyclic, want)
+	}
+}
+
+func TestAffectedBy(t *testing.T) {
+	tests := []struct {
+		name         string
+		dependencies map[string][]string
+		changed      []string
+		want         []string
+	}{
+		{
+			name: "chain",
+			// A depends on B, which depends on C
+			dependencies: map[string][]string{"A": {"B"}, "B": {"C"}, "C": nil, "D": nil},
+			changed:      []string{"C"},
+			want:         []string{"C", "B", "A"},
+		},
+		{
+			name:         "middle of a chain",
+			dependencies: map[string][]string{"A": {"B"}, "B": {"C"}, "C": nil},
+			changed:      []string{"B"},
+			want:         []string{"B", "A"},
+		},
+		{
+			name: "diamond",
+			// A depends on B and C, which both depend on D
+			dependencies: map[string][]string{"A": {"B", "C"}, "B": {"D"}, "C": {"D"}, "D": nil},
+			changed:      []string{"D"},
+			want:         []string{"D", "B", "C", "A"},
+		},
+		{
+			name:         "one side of a diamond",
+			dependencies: map[string][]string{"A": {"B", "C"}, "B": {"D"}, "C": {"D"}, "D": nil},
+			changed:      []string{"C"},
+			want:         []string{"C", "A"},
+		},
+		{
+			name:         "several changes",
+			dependencies: map[string][]string{"A": {"B"}, "B": nil, "C": nil, "D": {"C"}},
+			changed:      []string{"B", "C"},
+			want:         []string{"B", "A", "C", "D"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(projectsWithDependencies(tt.dependencies)).AffectedBy(tt.changed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("AffectedBy(%v) = %v, want %v", tt.changed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAffectedByUnknownProject(t *testing.T) {
+	r := New(projectsWithDependencies(map[string][]string{"A": nil}))
+
+	if _, err := r.AffectedBy([]string{"missing"}); err == nil {
+		t.Fatal("AffectedBy() succeeded on an unknown project, want an error")
+	}
+}
@@ -0,0 +1,190 @@
+package sbom
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"duck/internal/dependencyscanner"
+)
+
+// SpecVersion is the CycloneDX specification version of generated documents
+const SpecVersion = "1.5"
+
+// BOM is a CycloneDX JSON document
+type BOM struct {
+	BOMFormat    string       `json:"bomFormat"`
+	SpecVersion  string       `json:"specVersion"`
+	SerialNumber string       `json:"serialNumber,omitempty"`
+	Version      int          `json:"version"`
+	Metadata     Metadata     `json:"metadata"`
+	Components   []Component  `json:"components"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// Metadata describes when and by what the BOM was generated
+type Metadata struct {
+	Timestamp string     `json:"timestamp"`
+	Tools     *Tools     `json:"tools,omitempty"`
+	Component *Component `json:"component,omitempty"`
+}
+
+// Tools lists the tools that produced the BOM
+type Tools struct {
+	Components []Component `json:"components"`
+}
+
+// Component is a library or internal project in the BOM
+type Component struct {
+	Type       string     `json:"type"`
+	BOMRef     string     `json:"bom-ref,omitempty"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version,omitempty"`
+	Purl       string     `json:"purl,omitempty"`
+	Properties []Property `json:"properties,omitempty"`
+}
+
+// Property is a name/value annotation on a component
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Dependency lists the components a component depends on
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// Options describes the workspace the BOM is generated for
+type Options struct {
+	// Name is the name of the root component, usually the workspace directory
+	Name string
+	// ToolVersion is the duck version recorded in the metadata
+	ToolVersion string
+	// IsInternal reports whether a dependency is one of the workspace's own modules;
+	// internal dependencies are not listed as components
+	IsInternal func(module string) bool
+}
+
+// Build aggregates the external dependencies of every project in graph into a
+// CycloneDX BOM. Each project becomes an application component that depends on
+// the libraries it uses, and each library records the projects using it.
+func Build(graph *dependencyscanner.DependencyGraph, options Options) *BOM {
+	projects := graph.GetProjectsWithDependencies()
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].ProjectPath < projects[j].ProjectPath
+	})
+
+	libraries := make(map[string]*Component)
+	usedBy := make(map[string][]string)
+
+	bom := &BOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  SpecVersion,
+		SerialNumber: newSerialNumber(),
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: &Tools{
+				Components: []Component{{Type: "application", Name: "duck", Version: options.ToolVersion}},
+			},
+			Component: &Component{Type: "application", BOMRef: "workspace", Name: options.Name},
+		},
+		Components: make([]Component, 0),
+	}
+
+	rootDependency := Dependency{Ref: "workspace", DependsOn: make([]string, 0)}
+	var projectDependencies []Dependency
+
+	for _, project := range projects {
+		projectRef := "project:" + project.ProjectPath
+		bom.Components = append(bom.Components, Component{
+			Type:   "application",
+			BOMRef: projectRef,
+			Name:   project.ProjectPath,
+			Properties: []Property{
+				{Name: "duck:language", Value: project.Language},
+			},
+		})
+		rootDependency.DependsOn = append(rootDependency.DependsOn, projectRef)
+
+		dependency := Dependency{Ref: projectRef, DependsOn: make([]string, 0)}
+		for _, dep := range project.Dependencies {
+			if options.IsInternal != nil && options.IsInternal(dep.Target) {
+				continue
+			}
+
+			purl := packageURL(project.Language, dep.Target, dep.Version)
+			if _, exists := libraries[purl]; !exists {
+				libraries[purl] = &Component{
+					Type:    "library",
+					BOMRef:  purl,
+					Name:    dep.Target,
+					Version: dep.Version,
+					Purl:    purl,
+				}
+			}
+			usedBy[purl] = append(usedBy[purl], project.ProjectPath)
+			dependency.DependsOn = append(dependency.DependsOn, purl)
+		}
+		projectDependencies = append(projectDependencies, dependency)
+	}
+
+	refs := make([]string, 0, len(libraries))
+	for ref := range libraries {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	for _, ref := range refs {
+		library := libraries[ref]
+		for _, projectPath := range usedBy[ref] {
+			library.Properties = append(library.Properties, Property{Name: "duck:usedBy", Value: projectPath})
+		}
+		bom.Components = append(bom.Components, *library)
+	}
+
+	bom.Dependencies = append([]Dependency{rootDependency}, projectDependencies...)
+
+	return bom
+}
+
+// Write encodes bom as indented JSON
+func Write(w io.Writer, bom *BOM) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bom)
+}
+
+// packageURL builds the purl identifying a dependency of a project in language
+func packageURL(language, name, version string) string {
+	purlType := language
+	switch language {
+	case "go":
+		purlType = "golang"
+	case "javascript":
+		purlType = "npm"
+	}
+
+	purl := fmt.Sprintf("pkg:%s/%s", purlType, name)
+	if version != "" {
+		purl += "@" + version
+	}
+	return purl
+}
+
+// newSerialNumber returns a random RFC 4122 UUID URN
+func newSerialNumber() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return ""
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
@@ -0,0 +1,147 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"duck/internal/config"
+)
+
+// CacheDir is the directory, relative to the workspace root, where duck keeps its caches
+const CacheDir = ".duck"
+
+// CacheFileName is the name of the scan cache file inside CacheDir
+const CacheFileName = "cache.json"
+
+// scanCache stores parsed project configs keyed by config file path, so unchanged
+// files do not need to be parsed again on the next scan
+type scanCache struct {
+	path      string
+	workspace string
+	mu        sync.Mutex
+	entries   map[string]cacheEntry // Entries loaded from disk
+	seen      map[string]cacheEntry // Entries for config files found during this scan
+}
+
+type cacheEntry struct {
+	Fingerprint string            `json:"fingerprint"`
+	Config      *config.AppConfig `json:"config"`
+}
+
+type cacheFile struct {
+	Workspace string                `json:"workspace"` // Fingerprint of the duck.yaml settings the entries were scanned with
+	Entries   map[string]cacheEntry `json:"entries"`
+}
+
+// loadScanCache reads the cache at path. Entries written for a different
+// workspace fingerprint are discarded.
+func loadScanCache(path, workspace string) *scanCache {
+	cache := &scanCache{
+		path:      path,
+		workspace: workspace,
+		entries:   make(map[string]cacheEntry),
+		seen:      make(map[string]cacheEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		// A corrupt cache is treated as empty and rewritten after the scan
+		return cache
+	}
+	if file.Entries != nil && file.Workspace == workspace {
+		cache.entries = file.Entries
+	}
+
+	return cache
+}
+
+// lookup returns the cached config for configPath if the file is unchanged
+func (c *scanCache) lookup(configPath string, info os.FileInfo) (*config.AppConfig, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[configPath]
+	if !exists || entry.Config == nil || entry.Fingerprint != fingerprint(configPath, info) {
+		return nil, false
+	}
+
+	c.seen[configPath] = entry
+	return entry.Config, true
+}
+
+// store records a freshly parsed config for configPath
+func (c *scanCache) store(configPath string, info os.FileInfo, appConfig *config.AppConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seen[configPath] = cacheEntry{
+		Fingerprint: fingerprint(configPath, info),
+		Config:      appConfig,
+	}
+}
+
+// save writes the entries seen during this scan, which evicts projects whose
+// config files no longer exist
+func (c *scanCache) save() error {
+	data, err := json.Marshal(cacheFile{Workspace: c.workspace, Entries: c.seen})
+	if err != nil {
+		return fmt.Errorf("failed to encode scan cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+
+	return nil
+}
+
+// fingerprint hashes a config file's path, modification time and size
+func fingerprint(configPath string, info os.FileInfo) string {
+	sum := sha256.Sum256([]byte(configPath + "\x00" + strconv.FormatInt(info.ModTime().UnixNano(), 10) +
+		"\x00" + strconv.FormatInt(info.Size(), 10)))
+	return hex.EncodeToString(sum[:])
+}
+
+// workspaceFingerprint hashes the duck.yaml settings that decide which config
+// files are scanned and how they are parsed
+func workspaceFingerprint(projectConfig *config.ProjectConfig) string {
+	data, _ := json.Marshal(struct {
+		Format                config.ProjectConfigFormat
+		Filenames             []string
+		TargetDirectory       string
+		AdditionalDirectories []string
+		Ignore                string
+	}{
+		Format:                projectConfig.ProjectConfigFormat,
+		Filenames:             projectConfig.ProjectConfigFilenames,
+		TargetDirectory:       projectConfig.TargetDirectory,
+		AdditionalDirectories: projectConfig.AdditionalDirectories,
+		Ignore:                projectConfig.Ignore.String(),
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ClearCache removes the scan cache from the given workspace root
+func ClearCache(workspaceRoot string) error {
+	err := os.Remove(filepath.Join(workspaceRoot, CacheDir, CacheFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove scan cache: %w", err)
+	}
+	return nil
+}
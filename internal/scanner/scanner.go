@@ -4,21 +4,72 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 
 	"duck/internal/config"
 )
 
+// scanJob is a discovered project config file waiting to be parsed
+type scanJob struct {
+	path           string
+	configFileName string
+	info           os.FileInfo
+}
+
 type Scanner struct {
 	projectConfig *config.ProjectConfig
 	projects      map[string]*config.AppProject
-	workspaceRoot string // Cache the workspace root to avoid repeated os.Getwd() calls
+	loadErrors    map[string]error    // Config files that failed to load, keyed by path
+	duplicateKeys map[string][]string // Config files that resolved to the same project key
+	workspaceRoot string              // Cache the workspace root to avoid repeated os.Getwd() calls
+	useCache      bool
+	cacheReadOnly bool
+	cache         *scanCache
+	workers       int
+	mu            sync.Mutex // Guards projects and loadErrors while workers are running
 }
 
 func New(projectConfig *config.ProjectConfig) *Scanner {
 	return &Scanner{
 		projectConfig: projectConfig,
 		projects:      make(map[string]*config.AppProject),
+		loadErrors:    make(map[string]error),
+		duplicateKeys: make(map[string][]string),
+		workers:       defaultWorkers(),
+	}
+}
+
+// defaultWorkers sizes the parser pool. Parsing is I/O bound, so use more
+// workers than CPUs to keep slow (e.g. network) filesystems busy.
+func defaultWorkers() int {
+	workers := runtime.NumCPU() * 2
+	if workers < 4 {
+		workers = 4
+	}
+	return workers
+}
+
+// SetCacheEnabled turns the on-disk scan cache on or off for subsequent scans
+func (s *Scanner) SetCacheEnabled(enabled bool) {
+	s.useCache = enabled
+}
+
+// SetCacheReadOnly makes the scanner use the on-disk scan cache without
+// writing it back after a scan
+func (s *Scanner) SetCacheReadOnly(readOnly bool) {
+	s.cacheReadOnly = readOnly
+}
+
+// SetWorkers sets how many config files are parsed concurrently
+func (s *Scanner) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
 	}
+	s.workers = workers
 }
 
 func (s *Scanner) ScanProjects() error {
@@ -29,39 +80,84 @@ func (s *Scanner) ScanProjects() error {
 	}
 	s.workspaceRoot = cwd
 
+	if s.useCache {
+		s.cache = loadScanCache(filepath.Join(s.workspaceRoot, CacheDir, CacheFileName), workspaceFingerprint(s.projectConfig))
+	}
+
 	targetDir := s.projectConfig.TargetDirectory
 
-	var scanAll bool
-	var configFileNames []string
-
-	switch s.projectConfig.ProjectConfigFormat {
-	case config.FormatDuck:
-		configFileNames = []string{"app.yaml"}
-	case config.FormatNx:
-		configFileNames = []string{"project.json"}
-	case config.FormatAll:
-		configFileNames = []string{"app.yaml", "project.json"}
-		scanAll = true
-	default:
+	configFileNames := s.projectConfig.ConfigFileNames()
+	if len(configFileNames) == 0 {
 		return fmt.Errorf("unsupported project config format: %s", s.projectConfig.ProjectConfigFormat)
 	}
 
-	// Scan target directory
-	if err := s.scanDirectory(targetDir, configFileNames, scanAll); err != nil {
-		return err
+	// Walking only discovers config files; a bounded pool of workers parses them
+	jobs := make(chan scanJob)
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				s.processConfig(job)
+			}
+		}()
+	}
+
+	// Walk the target directory and each additional directory concurrently,
+	// one walker per directory, all feeding the same parser pool
+	dirs := append([]string{targetDir}, s.projectConfig.AdditionalDirectories...)
+	walkErrs := make([]error, len(dirs))
+	var walkers sync.WaitGroup
+	for i, dir := range dirs {
+		walkers.Add(1)
+		go func() {
+			defer walkers.Done()
+			walkErrs[i] = s.scanDirectory(dir, configFileNames, jobs)
+		}()
 	}
 
-	// Scan additional directories
-	for _, additionalDir := range s.projectConfig.AdditionalDirectories {
-		if err := s.scanDirectory(additionalDir, configFileNames, scanAll); err != nil {
-			return err
+	walkers.Wait()
+	close(jobs)
+	wg.Wait()
+
+	// Report the first failing directory in configuration order
+	for _, walkErr := range walkErrs {
+		if walkErr != nil {
+			return walkErr
+		}
+	}
+
+	if s.cache != nil && !s.cacheReadOnly {
+		if err := s.cache.save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
 	}
 
+	if custom := s.projectConfig.ProjectConfigFilenames; len(custom) > 0 && slices.Contains(configFileNames, custom[0]) && !s.foundConfigFile(custom) {
+		fmt.Fprintf(os.Stderr, "Warning: no project config files named %s were found\n", strings.Join(custom, ", "))
+	}
+
 	return nil
 }
 
-func (s *Scanner) scanDirectory(targetDir string, configFileNames []string, scanAll bool) error {
+// foundConfigFile reports whether the last scan found a config file with one of names
+func (s *Scanner) foundConfigFile(names []string) bool {
+	for _, project := range s.projects {
+		if slices.Contains(names, filepath.Base(project.ConfigFile)) {
+			return true
+		}
+	}
+	for path := range s.loadErrors {
+		if slices.Contains(names, filepath.Base(path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanDirectory walks targetDir and sends every project config file to jobs
+func (s *Scanner) scanDirectory(targetDir string, configFileNames []string, jobs chan<- scanJob) error {
 	return filepath.Walk(targetDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			if os.IsPermission(err) {
@@ -70,64 +166,182 @@ func (s *Scanner) scanDirectory(targetDir string, configFileNames []string, scan
 			return err
 		}
 
-		for _, configFileName := range configFileNames {
+		if info.IsDir() {
+			if path != targetDir && s.projectConfig.Ignore.Match(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		for i, configFileName := range configFileNames {
 			if info.Name() == configFileName {
 				projectDir := filepath.Dir(path)
 
-				if scanAll {
-					var hasAppYaml, hasProjectJson bool
-					if configFileName == "app.yaml" {
-						hasAppYaml = true
-						if _, err := os.Stat(filepath.Join(projectDir, "project.json")); err == nil {
-							hasProjectJson = true
-						}
-					} else if configFileName == "project.json" {
-						hasProjectJson = true
-						if _, err := os.Stat(filepath.Join(projectDir, "app.yaml")); err == nil {
-							hasAppYaml = true
-						}
-					}
-
-					if hasAppYaml && hasProjectJson && configFileName == "project.json" {
+				// When a format scans several files, e.g. "all", the earlier
+				// ones win over the later ones in the same directory
+				for _, preferred := range configFileNames[:i] {
+					if _, err := os.Stat(filepath.Join(projectDir, preferred)); err == nil {
 						return nil
 					}
 				}
 
-				var appConfig *config.AppConfig
-				var loadErr error
+				jobs <- scanJob{path: path, configFileName: configFileName, info: info}
+				break
+			}
+		}
 
-				if configFileName == "app.yaml" {
-					appConfig, loadErr = config.LoadAppConfig(path)
-				} else if configFileName == "project.json" {
-					appConfig, loadErr = config.LoadNxProjectConfig(path)
-				}
+		return nil
+	})
+}
 
-				if loadErr != nil {
-					fmt.Printf("Warning: Failed to load project config at %s: %v\n", path, loadErr)
-					return nil
-				}
+// processConfig parses a discovered config file and records the project
+func (s *Scanner) processConfig(job scanJob) {
+	appConfig, loadErr := s.loadConfig(job.path, job.configFileName, job.info)
 
-				// Use relative path from workspace root as project key for consistency
-				// Use cached workspace root for performance
-				relPath, err := filepath.Rel(s.workspaceRoot, projectDir)
-				if err != nil {
-					// Fallback to namespace/name if relative path fails
-					relPath = fmt.Sprintf("%s/%s", appConfig.Namespace, appConfig.Name)
-				}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-				projectKey := relPath
+	if loadErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load project config at %s: %v\n", job.path, loadErr)
+		s.loadErrors[job.path] = loadErr
+		return
+	}
 
-				s.projects[projectKey] = &config.AppProject{
-					Config: appConfig,
-					Path:   projectDir,
-				}
+	projectDir := filepath.Dir(job.path)
 
-				break
-			}
+	// Use relative path from workspace root as project key for consistency
+	// Use cached workspace root for performance
+	relPath, err := filepath.Rel(s.workspaceRoot, projectDir)
+	if err != nil {
+		// Fallback to namespace/name if relative path fails
+		relPath = fmt.Sprintf("%s/%s", appConfig.Namespace, appConfig.Name)
+	}
+
+	projectKey := relPath
+
+	if existing, exists := s.projects[projectKey]; exists {
+		// Overlapping target and additional directories reach the same file twice
+		if existing.ConfigFile == job.path {
+			return
 		}
 
-		return nil
-	})
+		s.recordDuplicateKey(projectKey, existing.ConfigFile, job.path)
+
+		// Keep the same project whatever order the files were found in
+		if existing.ConfigFile < job.path {
+			return
+		}
+	}
+
+	s.projects[projectKey] = &config.AppProject{
+		Config:     appConfig,
+		Path:       projectDir,
+		ConfigFile: job.path,
+	}
+}
+
+// recordDuplicateKey notes that the given config files resolve to projectKey
+func (s *Scanner) recordDuplicateKey(projectKey string, configFiles ...string) {
+	files := s.duplicateKeys[projectKey]
+	for _, configFile := range configFiles {
+		if !slices.Contains(files, configFile) {
+			files = append(files, configFile)
+		}
+	}
+	sort.Strings(files)
+	s.duplicateKeys[projectKey] = files
+}
+
+// loadConfig parses a project config file, reusing the cached result when the
+// file has not changed since the last scan
+func (s *Scanner) loadConfig(path, configFileName string, info os.FileInfo) (*config.AppConfig, error) {
+	if s.cache != nil {
+		if appConfig, ok := s.cache.lookup(path, info); ok {
+			return appConfig, nil
+		}
+	}
+
+	var appConfig *config.AppConfig
+	var err error
+
+	// app.yaml, or a file named in projectConfigFilenames, is read as YAML
+	if configFileName == "project.json" {
+		appConfig, err = config.LoadNxProjectConfig(path)
+	} else if configFileName == "project.toml" {
+		appConfig, err = config.LoadTomlProjectConfig(path)
+	} else {
+		appConfig, err = config.LoadAppConfig(path)
+	}
+
+	if err == nil && s.cache != nil {
+		s.cache.store(path, info, appConfig)
+	}
+
+	return appConfig, err
+}
+
+// GetLoadErrors returns the config files that could not be loaded during the
+// last scan, keyed by file path
+func (s *Scanner) GetLoadErrors() map[string]error {
+	return s.loadErrors
+}
+
+// GetDuplicateKeys returns the project keys that more than one config file
+// resolved to during the last scan, mapped to the sorted config file paths. The
+// project loaded from the first of those paths is the one that was kept.
+func (s *Scanner) GetDuplicateKeys() map[string][]string {
+	return s.duplicateKeys
+}
+
+// DuplicateKeyError reports config files that resolve to the same project key.
+// The scan keeps the project of the first file in path order.
+type DuplicateKeyError struct {
+	Key         string
+	ConfigFiles []string // Sorted
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("project key '%s' is claimed by multiple config files: %s (using %s)", e.Key, strings.Join(e.ConfigFiles, ", "), e.ConfigFiles[0])
+}
+
+// DuplicateKeyErrors returns a DuplicateKeyError for each project key claimed
+// by several config files in the last scan, sorted by key
+func (s *Scanner) DuplicateKeyErrors() []error {
+	keys := make([]string, 0, len(s.duplicateKeys))
+	for key := range s.duplicateKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := make([]error, 0, len(keys))
+	for _, key := range keys {
+		errs = append(errs, &DuplicateKeyError{Key: key, ConfigFiles: s.duplicateKeys[key]})
+	}
+	return errs
+}
+
+// GetDuplicateNames returns project names and aliases shared by more than one
+// project, mapped to the sorted keys of the projects using them
+func (s *Scanner) GetDuplicateNames() map[string][]string {
+	byName := make(map[string][]string)
+	for key, project := range s.projects {
+		// A project listing its own name as an alias is not a duplicate
+		names := project.Config.Names()
+		slices.Sort(names)
+		for _, name := range slices.Compact(names) {
+			byName[name] = append(byName[name], key)
+		}
+	}
+
+	duplicates := make(map[string][]string)
+	for name, keys := range byName {
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			duplicates[name] = keys
+		}
+	}
+
+	return duplicates
 }
 
 func (s *Scanner) GetProjects() map[string]*config.AppProject {
@@ -156,7 +370,7 @@ func (s *Scanner) GetAvailableScripts(project *config.AppProject) []string {
 	var availableScripts []string
 
 	for scriptName := range s.projectConfig.Scripts {
-		if enabled, exists := project.Config.Scripts[scriptName]; !exists || enabled {
+		if project.Config.ScriptEnabled(scriptName) {
 			availableScripts = append(availableScripts, scriptName)
 		}
 	}
@@ -0,0 +1,227 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"duck/internal/config"
+)
+
+// writeFile creates path below dir with content, and its parent directories
+func writeFile(t *testing.T, dir, path, content string) string {
+	t.Helper()
+	fullPath := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fullPath
+}
+
+func TestDuplicateProjectKey(t *testing.T) {
+	workspace := t.TempDir()
+	appYAML := writeFile(t, workspace, "apps/api/app.yaml", "name: api\nnamespace: core\n")
+	projectJSON := writeFile(t, workspace, "apps/api/project.json", `{"name": "api-nx"}`)
+
+	s := New(&config.ProjectConfig{ProjectConfigFormat: config.FormatAll})
+	s.workspaceRoot = workspace
+
+	// Both files resolve to the key of their directory. Feed them in reverse
+	// path order: the scan must still keep the first in path order.
+	for _, job := range []struct{ path, name string }{{projectJSON, "project.json"}, {appYAML, "app.yaml"}} {
+		info, err := os.Stat(job.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.processConfig(scanJob{path: job.path, configFileName: job.name, info: info})
+	}
+
+	project, exists := s.GetProject("apps/api")
+	if !exists {
+		t.Fatal("project apps/api not found")
+	}
+	if project.ConfigFile != appYAML || project.Config.Name != "api" {
+		t.Errorf("kept %s (%s), want %s (api)", project.ConfigFile, project.Config.Name, appYAML)
+	}
+
+	errs := s.DuplicateKeyErrors()
+	if len(errs) != 1 {
+		t.Fatalf("got %d duplicate key errors %v, want 1", len(errs), errs)
+	}
+	want := "project key 'apps/api' is claimed by multiple config files: " + appYAML + ", " + projectJSON + " (using " + appYAML + ")"
+	if got := errs[0].Error(); got != want {
+		t.Errorf("error = %q\nwant    %q", got, want)
+	}
+}
+
+func TestSameConfigFileTwiceIsNotDuplicate(t *testing.T) {
+	workspace := t.TempDir()
+	appYAML := writeFile(t, workspace, "apps/api/app.yaml", "name: api\n")
+
+	s := New(&config.ProjectConfig{ProjectConfigFormat: config.FormatDuck})
+	s.workspaceRoot = workspace
+
+	// Overlapping target and additional directories reach the same file twice
+	info, err := os.Stat(appYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		s.processConfig(scanJob{path: appYAML, configFileName: "app.yaml", info: info})
+	}
+
+	if errs := s.DuplicateKeyErrors(); len(errs) != 0 {
+		t.Errorf("got duplicate key errors %v, want none", errs)
+	}
+}
+
+// BenchmarkScanProjects scans a synthetic workspace of a few hundred projects
+// with one parser and with the default pool
+func BenchmarkScanProjects(b *testing.B) {
+	workspace := b.TempDir()
+	for i := 0; i < 300; i++ {
+		dir := filepath.Join(workspace, "apps", fmt.Sprintf("ns%d", i%10), fmt.Sprintf("app%d", i))
+		if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+			b.Fatal(err)
+		}
+		content := fmt.Sprintf("name: app%d\nnamespace: ns%d\ndependencies: []\ntags: [bench]\n", i, i%10)
+		if err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(content), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	originalCwd, err := os.Getwd()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := os.Chdir(workspace); err != nil {
+		b.Fatal(err)
+	}
+	defer os.Chdir(originalCwd)
+
+	for _, workers := range []int{1, defaultWorkers()} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s := New(&config.ProjectConfig{
+					TargetDirectory:     filepath.Join(workspace, "apps"),
+					ProjectConfigFormat: config.FormatDuck,
+				})
+				s.SetWorkers(workers)
+				if err := s.ScanProjects(); err != nil {
+					b.Fatal(err)
+				}
+				if len(s.GetProjects()) != 300 {
+					b.Fatalf("found %d projects, want 300", len(s.GetProjects()))
+				}
+			}
+		})
+	}
+}
+
+// scanWithCache scans the apps directory of workspace, which must be the
+// working directory, with the scan cache enabled
+func scanWithCache(t *testing.T, workspace string, additionalDirectories ...string) *Scanner {
+	t.Helper()
+	s := New(&config.ProjectConfig{
+		TargetDirectory:       filepath.Join(workspace, "apps"),
+		AdditionalDirectories: additionalDirectories,
+		ProjectConfigFormat:   config.FormatDuck,
+	})
+	s.SetCacheEnabled(true)
+	if err := s.ScanProjects(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// readScanCache returns the scan cache written to workspace
+func readScanCache(t *testing.T, workspace string) cacheFile {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(workspace, CacheDir, CacheFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// projectName returns the name of the project s found at key, or "" if there is none
+func projectName(s *Scanner, key string) string {
+	if project, exists := s.GetProject(key); exists {
+		return project.Config.Name
+	}
+	return ""
+}
+
+func TestScanCache(t *testing.T) {
+	workspace := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(workspace); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	writeFile(t, workspace, "apps/api/app.yaml", "name: api\n")
+	web := writeFile(t, workspace, "apps/web/app.yaml", "name: web\n")
+	worker := writeFile(t, workspace, "apps/worker/app.yaml", "name: worker\n")
+
+	scanWithCache(t, workspace)
+	file := readScanCache(t, workspace)
+	if len(file.Entries) != 3 {
+		t.Fatalf("cache has %d entries after the first scan, want 3", len(file.Entries))
+	}
+
+	// Mark the cached api config, so a rescan shows whether it was reused
+	api := filepath.Join(workspace, "apps/api/app.yaml")
+	file.Entries[api].Config.Name = "api-cached"
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, workspace, filepath.Join(CacheDir, CacheFileName), string(data))
+
+	writeFile(t, workspace, "apps/web/app.yaml", "name: web-renamed\n")
+	if err := os.Remove(worker); err != nil {
+		t.Fatal(err)
+	}
+
+	s := scanWithCache(t, workspace)
+	if got := projectName(s, "apps/api"); got != "api-cached" {
+		t.Errorf("unchanged project name = %q, want the cached config to be reused", got)
+	}
+	if got := projectName(s, "apps/web"); got != "web-renamed" {
+		t.Errorf("edited project name = %q, want it to be parsed again", got)
+	}
+	if got := projectName(s, "apps/worker"); got != "" {
+		t.Errorf("deleted project still found as %q", got)
+	}
+
+	file = readScanCache(t, workspace)
+	if _, exists := file.Entries[worker]; exists {
+		t.Error("deleted project was not evicted from the cache")
+	}
+	if entry, exists := file.Entries[web]; !exists || entry.Config.Name != "web-renamed" {
+		t.Errorf("cache entry for the edited project = %+v, want the new config", entry.Config)
+	}
+
+	// Scanning with different duck.yaml settings discards every cached config
+	empty := filepath.Join(workspace, "empty")
+	if err := os.Mkdir(empty, 0755); err != nil {
+		t.Fatal(err)
+	}
+	s = scanWithCache(t, workspace, empty)
+	if got := projectName(s, "apps/api"); got != "api" {
+		t.Errorf("project name = %q after the workspace settings changed, want it to be parsed again", got)
+	}
+}
@@ -0,0 +1,160 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Dir is the directory, relative to the workspace root, where run state is kept
+var Dir = filepath.Join(".duck", "state")
+
+// FileName is the name of the run state file inside Dir
+const FileName = "runs.json"
+
+// State is what duck remembers between runs of a workspace
+type State struct {
+	Scripts map[string]*ScriptState `json:"scripts"`
+
+	path string
+}
+
+// DurationHistory is how many successful runs of a script are remembered per
+// project to compute its average duration
+const DurationHistory = 10
+
+// ScriptState is the recorded outcome of runs of a single script
+type ScriptState struct {
+	// Failed holds the keys of projects whose most recent run of the script failed
+	Failed []string `json:"failed"`
+	// Durations holds the durations of the most recent successful runs per project, oldest first
+	Durations map[string][]time.Duration `json:"durations,omitempty"`
+	UpdatedAt time.Time                  `json:"updatedAt"`
+}
+
+// Load reads the run state of the workspace at workspaceRoot. A missing state
+// file yields an empty state.
+func Load(workspaceRoot string) (*State, error) {
+	s := &State{
+		Scripts: make(map[string]*ScriptState),
+		path:    filepath.Join(workspaceRoot, Dir, FileName),
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse run state %s: %w", s.path, err)
+	}
+	if s.Scripts == nil {
+		s.Scripts = make(map[string]*ScriptState)
+	}
+
+	return s, nil
+}
+
+// Save writes the state back to the workspace
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write run state: %w", err)
+	}
+
+	return nil
+}
+
+// FailedProjects returns the projects whose most recent run of script failed
+func (s *State) FailedProjects(script string) []string {
+	if scriptState, exists := s.Scripts[script]; exists {
+		return append([]string(nil), scriptState.Failed...)
+	}
+	return nil
+}
+
+// AverageDuration returns the average duration of the recorded successful runs
+// of script on project, and false when none were recorded
+func (s *State) AverageDuration(script, project string) (time.Duration, bool) {
+	scriptState, exists := s.Scripts[script]
+	if !exists || len(scriptState.Durations[project]) == 0 {
+		return 0, false
+	}
+
+	var total time.Duration
+	for _, duration := range scriptState.Durations[project] {
+		total += duration
+	}
+	return total / time.Duration(len(scriptState.Durations[project])), true
+}
+
+// RecordDurations adds the durations of successful runs of script, keeping the
+// last DurationHistory runs of each project
+func (s *State) RecordDurations(script string, durations map[string]time.Duration) {
+	if len(durations) == 0 {
+		return
+	}
+
+	scriptState := s.scriptState(script)
+	if scriptState.Durations == nil {
+		scriptState.Durations = make(map[string][]time.Duration)
+	}
+
+	for project, duration := range durations {
+		history := append(scriptState.Durations[project], duration)
+		if len(history) > DurationHistory {
+			history = history[len(history)-DurationHistory:]
+		}
+		scriptState.Durations[project] = history
+	}
+	scriptState.UpdatedAt = time.Now()
+}
+
+// scriptState returns the state of script, creating it when needed
+func (s *State) scriptState(script string) *ScriptState {
+	scriptState, exists := s.Scripts[script]
+	if !exists {
+		scriptState = &ScriptState{}
+		s.Scripts[script] = scriptState
+	}
+	return scriptState
+}
+
+// RecordResults updates the failures recorded for script: passed projects are
+// cleared and failed projects are added. Projects that did not run keep their
+// previous outcome.
+func (s *State) RecordResults(script string, passed, failed []string) {
+	scriptState := s.scriptState(script)
+
+	failedSet := make(map[string]bool)
+	for _, key := range scriptState.Failed {
+		failedSet[key] = true
+	}
+	for _, key := range passed {
+		delete(failedSet, key)
+	}
+	for _, key := range failed {
+		failedSet[key] = true
+	}
+
+	scriptState.Failed = make([]string, 0, len(failedSet))
+	for key := range failedSet {
+		scriptState.Failed = append(scriptState.Failed, key)
+	}
+	sort.Strings(scriptState.Failed)
+	scriptState.UpdatedAt = time.Now()
+}
@@ -1,16 +1,32 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"duck/internal/cli"
 )
 
+// exitInterrupted is the exit code of a command stopped by SIGINT or SIGTERM,
+// following the shell convention of 128+SIGINT
+const exitInterrupted = 130
+
 func main() {
 	app := cli.CreateApp()
 
-	if err := app.Run(os.Args); err != nil {
+	// Commands see the signal as a cancelled context and stop their scripts
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		if ctx.Err() != nil {
+			log.Print(err)
+			stop()
+			os.Exit(exitInterrupted)
+		}
 		log.Fatal(err)
 	}
 }
@@ -1,11 +1,25 @@
 package common
 
-import "os"
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
 
 // Config holds common configuration
 type Config struct {
-	AppName string
-	Port    string
+	AppName  string
+	Port     string
+	LogLevel string
+	// Values holds extra settings from the config file
+	Values map[string]string
+}
+
+// fileConfig is the JSON layout read by NewConfigFromFile
+type fileConfig struct {
+	Port     json.Number       `json:"port"`
+	LogLevel string            `json:"logLevel"`
+	Values   map[string]string `json:"values"`
 }
 
 // NewConfig creates a new config instance
@@ -14,8 +28,53 @@ func NewConfig(appName string) *Config {
 	if port == "" {
 		port = "8080"
 	}
+	logLevel := os.Getenv(LogLevelEnv)
+	if logLevel == "" {
+		logLevel = "info"
+	}
 	return &Config{
-		AppName: appName,
-		Port:    port,
+		AppName:  appName,
+		Port:     port,
+		LogLevel: logLevel,
+		Values:   make(map[string]string),
+	}
+}
+
+// NewConfigFromFile creates a config from a JSON file such as
+//
+//	{"port": 9000, "logLevel": "debug", "values": {"region": "eu"}}
+//
+// Settings missing from the file fall back to the environment (PORT and
+// DUCK_LOG_LEVEL) and then to the defaults of NewConfig. A missing file yields
+// the same config as NewConfig; a file that cannot be parsed is an error.
+func NewConfigFromFile(appName, path string) (*Config, error) {
+	config := NewConfig(appName)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
 	}
+
+	var file fileConfig
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	if file.Port != "" {
+		config.Port = file.Port.String()
+	}
+	if file.LogLevel != "" {
+		if _, err := ParseLevel(file.LogLevel); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+		config.LogLevel = file.LogLevel
+	}
+	for key, value := range file.Values {
+		config.Values[key] = value
+	}
+
+	return config, nil
 }
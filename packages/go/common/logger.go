@@ -1,26 +1,202 @@
 package common
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 )
 
+// Level is the severity of a log message
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// LogLevelEnv is the environment variable that sets the default level of new loggers
+const LogLevelEnv = "DUCK_LOG_LEVEL"
+
+// String returns the name used for the level in log lines
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// ParseLevel parses a level name such as "debug" or "WARN"
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level: %s", name)
+}
+
+// Format selects how log lines are written
+type Format int
+
+const (
+	// TextFormat writes "[time] [LEVEL] prefix: message" lines
+	TextFormat Format = iota
+	// JSONFormat writes one JSON object per line with ts, level, prefix and msg
+	JSONFormat
+)
+
 // Logger provides basic logging functionality
 type Logger struct {
 	prefix string
+	level  Level
+	format Format
+	out    io.Writer // Destination of debug and info messages
+	err    io.Writer // Destination of warnings and errors
 }
 
-// NewLogger creates a new logger instance
+// jsonLine is a log line in JSONFormat
+type jsonLine struct {
+	Time    string `json:"ts"`
+	Level   string `json:"level"`
+	Prefix  string `json:"prefix"`
+	Message string `json:"msg"`
+}
+
+// NewLogger creates a new logger instance that writes every message to stdout.
+// Its level comes from DUCK_LOG_LEVEL and defaults to LevelInfo.
 func NewLogger(prefix string) *Logger {
-	return &Logger{prefix: prefix}
+	return NewLoggerWithWriter(prefix, os.Stdout, os.Stdout)
+}
+
+// NewJSONLogger creates a logger like NewLogger that writes JSON lines
+func NewJSONLogger(prefix string) *Logger {
+	logger := NewLogger(prefix)
+	logger.SetFormat(JSONFormat)
+	return logger
+}
+
+// NewLoggerWithWriter creates a logger that writes debug and info messages to out
+// and warnings and errors to err. A nil out defaults to os.Stdout and a nil err
+// to os.Stderr.
+func NewLoggerWithWriter(prefix string, out, err io.Writer) *Logger {
+	if out == nil {
+		out = os.Stdout
+	}
+	if err == nil {
+		err = os.Stderr
+	}
+	return &Logger{prefix: prefix, level: defaultLevel(), out: out, err: err}
+}
+
+// defaultLevel reads the level from DUCK_LOG_LEVEL, falling back to LevelInfo
+func defaultLevel() Level {
+	value := os.Getenv(LogLevelEnv)
+	if value == "" {
+		return LevelInfo
+	}
+
+	level, err := ParseLevel(value)
+	if err != nil {
+		return LevelInfo
+	}
+	return level
+}
+
+// SetLevel sets the minimum level of messages that are logged
+func (l *Logger) SetLevel(level Level) {
+	l.level = level
+}
+
+// SetFormat sets the format of log lines
+func (l *Logger) SetFormat(format Format) {
+	l.format = format
+}
+
+// Level returns the minimum level of messages that are logged
+func (l *Logger) Level() Level {
+	return l.level
+}
+
+// Debug logs a debug message
+func (l *Logger) Debug(message string) {
+	l.log(LevelDebug, message)
 }
 
 // Info logs an info message
 func (l *Logger) Info(message string) {
-	fmt.Printf("[%s] [INFO] %s: %s\n", time.Now().Format("2006-01-02 15:04:05"), l.prefix, message)
+	l.log(LevelInfo, message)
+}
+
+// Warn logs a warning message
+func (l *Logger) Warn(message string) {
+	l.log(LevelWarn, message)
 }
 
 // Error logs an error message
 func (l *Logger) Error(message string) {
-	fmt.Printf("[%s] [ERROR] %s: %s\n", time.Now().Format("2006-01-02 15:04:05"), l.prefix, message)
+	l.log(LevelError, message)
+}
+
+// Debugf logs a debug message formatted like fmt.Sprintf
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// Infof logs an info message formatted like fmt.Sprintf
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// Warnf logs a warning message formatted like fmt.Sprintf
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// Errorf logs an error message formatted like fmt.Sprintf
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(LevelError, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) log(level Level, message string) {
+	if level < l.level {
+		return
+	}
+
+	w := l.out
+	if level >= LevelWarn {
+		w = l.err
+	}
+
+	now := time.Now()
+	if l.format == JSONFormat {
+		line, err := json.Marshal(jsonLine{
+			Time:    now.Format(time.RFC3339),
+			Level:   strings.ToLower(level.String()),
+			Prefix:  l.prefix,
+			Message: message,
+		})
+		if err == nil {
+			fmt.Fprintf(w, "%s\n", line)
+			return
+		}
+	}
+
+	fmt.Fprintf(w, "[%s] [%s] %s: %s\n", now.Format("2006-01-02 15:04:05"), level, l.prefix, message)
 }
@@ -1,9 +1,16 @@
 package httputils
 
 import (
+	"bytes"
+	"context"
 	"duck/common"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -11,27 +18,114 @@ import (
 type Client struct {
 	client *http.Client
 	logger *common.Logger
+	retry  retryPolicy
 }
 
-// NewClient creates a new HTTP client
-func NewClient() *Client {
-	return &Client{
+// NewClient creates a new HTTP client. Without options requests are not retried.
+func NewClient(options ...Option) *Client {
+	c := &Client{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 		logger: common.NewLogger("httputils"),
 	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
 }
 
 // Get performs a GET request
 func (c *Client) Get(url string) (*http.Response, error) {
-	c.logger.Info(fmt.Sprintf("GET request to %s", url))
-	return c.client.Get(url)
+	return c.GetWithContext(context.Background(), url)
 }
 
-// Post performs a POST request
+// GetWithContext performs a GET request that is cancelled with ctx
+func (c *Client) GetWithContext(ctx context.Context, url string) (*http.Response, error) {
+	c.logger.Infof("GET request to %s", url)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(ctx, req)
+}
+
+// Post performs a POST request. With an application/json content type the body
+// is encoded as JSON; otherwise it must be an io.Reader, []byte, string or nil.
 func (c *Client) Post(url, contentType string, body interface{}) (*http.Response, error) {
-	c.logger.Info(fmt.Sprintf("POST request to %s", url))
-	// Simplified for demo purposes
-	return nil, fmt.Errorf("not implemented")
+	return c.PostWithContext(context.Background(), url, contentType, body)
+}
+
+// PostWithContext performs a POST request like Post that is cancelled with ctx
+func (c *Client) PostWithContext(ctx context.Context, url, contentType string, body interface{}) (*http.Response, error) {
+	c.logger.Infof("POST request to %s", url)
+
+	reader, err := encodeBody(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return c.do(ctx, req)
+}
+
+// do sends req and logs when it fails because ctx was cancelled or timed out
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	resp, err := c.doWithRetry(ctx, req)
+	if err != nil {
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			c.logger.Warnf("%s request to %s timed out", req.Method, req.URL)
+		case errors.Is(ctx.Err(), context.Canceled):
+			c.logger.Warnf("%s request to %s was cancelled", req.Method, req.URL)
+		}
+	}
+	return resp, err
+}
+
+// encodeBody turns a Post body into the reader sent with the request
+func encodeBody(contentType string, body interface{}) (io.Reader, error) {
+	if isJSON(contentType) {
+		if reader, ok := body.(io.Reader); ok {
+			return reader, nil
+		}
+		if data, ok := body.([]byte); ok {
+			return bytes.NewReader(data), nil
+		}
+
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JSON body: %w", err)
+		}
+		return bytes.NewReader(data), nil
+	}
+
+	switch value := body.(type) {
+	case nil:
+		return nil, nil
+	case io.Reader:
+		return value, nil
+	case []byte:
+		return bytes.NewReader(value), nil
+	case string:
+		return strings.NewReader(value), nil
+	default:
+		return nil, fmt.Errorf("unsupported body type %T for content type %s", body, contentType)
+	}
+}
+
+// isJSON reports whether contentType is application/json, ignoring parameters such as charset
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json"
 }
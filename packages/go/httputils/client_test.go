@@ -0,0 +1,98 @@
+package httputils
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostJSON(t *testing.T) {
+	type payload struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+
+		var received payload
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		received.Count++
+		NewResponseWriter().WriteJSON(w, http.StatusCreated, received)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient().Post(server.URL, "application/json", payload{Name: "duck", Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("response Content-Type = %q, want application/json", got)
+	}
+
+	var response payload
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("response body is not JSON: %v", err)
+	}
+	if response != (payload{Name: "duck", Count: 2}) {
+		t.Errorf("response = %+v, want {Name:duck Count:2}", response)
+	}
+}
+
+func TestPostRawBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Content-Type"); got != "text/plain" {
+			t.Errorf("Content-Type = %q, want text/plain", got)
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient().Post(server.URL, "text/plain", []byte("quack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "quack" {
+		t.Errorf("echoed body = %q, want %q", body, "quack")
+	}
+}
+
+func TestPostUnsupportedBody(t *testing.T) {
+	if _, err := NewClient().Post("http://127.0.0.1", "text/plain", 42); err == nil {
+		t.Fatal("Post() with an int body for text/plain succeeded, want an error")
+	}
+}
+
+func TestPostNetworkError(t *testing.T) {
+	// A closed server refuses the connection
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	resp, err := NewClient().Post(url, "application/json", map[string]string{"name": "duck"})
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("Post() to a closed server succeeded, want a network error")
+	}
+	if resp != nil {
+		t.Errorf("response = %v, want nil on a network error", resp)
+	}
+}
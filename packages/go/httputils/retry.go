@@ -0,0 +1,116 @@
+package httputils
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Option configures a Client at construction
+type Option func(*Client)
+
+// retryPolicy describes when and how often failed requests are retried
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	retryPost   bool
+}
+
+// WithRetry retries GET requests that fail with a network error or a 502, 503
+// or 504 response, making at most maxAttempts attempts in total. The delay
+// before retry n is baseDelay*2^(n-1) with random jitter.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.retry.maxAttempts = maxAttempts
+		c.retry.baseDelay = baseDelay
+	}
+}
+
+// WithPostRetry lets WithRetry retry POST requests too. Only use it for
+// endpoints where repeating a request is safe. Bodies given as an io.Reader
+// other than *bytes.Reader, *bytes.Buffer or *strings.Reader cannot be replayed
+// and are never retried.
+func WithPostRetry() Option {
+	return func(c *Client) {
+		c.retry.retryPost = true
+	}
+}
+
+// shouldRetry reports whether a request that ended with resp and err may be
+// sent again
+func (p retryPolicy) shouldRetry(ctx context.Context, req *http.Request, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !p.retryPost || (req.Body != nil && req.GetBody == nil) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// delay returns the backoff before the retry following attempt, with up to 50%
+// random jitter subtracted
+func (p retryPolicy) delay(attempt int) time.Duration {
+	delay := p.baseDelay << (attempt - 1)
+	if delay <= 0 {
+		return 0
+	}
+	return delay - time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// doWithRetry sends req, retrying it according to the client's retry policy
+func (c *Client) doWithRetry(ctx context.Context, req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if attempt >= c.retry.maxAttempts || !c.retry.shouldRetry(ctx, req, resp, err) {
+			return resp, err
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		delay := c.retry.delay(attempt)
+		c.logger.Warnf("%s request to %s failed (%s), retrying in %v (attempt %d of %d)",
+			req.Method, req.URL, reason, delay.Truncate(time.Millisecond), attempt+1, c.retry.maxAttempts)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}